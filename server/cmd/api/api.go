@@ -34,6 +34,9 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	// activationLimiter throttles activation resends per email address
+	activationLimiter ratelimiter.Limiter
+	permissions       *permissionCache
 }
 
 type config struct {
@@ -46,6 +49,82 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	activation  activationConfig
+	outbox      outboxConfig
+	comments    commentsConfig
+	tags        tagsConfig
+	posts       postsConfig
+	suspensions suspensionsConfig
+	// permissionsTTL is how long the role to permission table is cached
+	permissionsTTL time.Duration
+}
+
+type suspensionsConfig struct {
+	// lifter marks expired suspensions as lifted. They stop being enforced
+	// as soon as they expire either way.
+	lifter lifterConfig
+}
+
+type lifterConfig struct {
+	enabled  bool
+	interval time.Duration
+}
+
+type postsConfig struct {
+	// restoreWindow is how long after deletion a post can be restored
+	restoreWindow time.Duration
+	purger        purgerConfig
+}
+
+type purgerConfig struct {
+	enabled  bool
+	interval time.Duration
+	// retention is how long the data is kept before being purged
+	retention time.Duration
+}
+
+type tagsConfig struct {
+	trending trendingConfig
+}
+
+type trendingConfig struct {
+	enabled bool
+	// interval is how often the trending tags are recomputed over the
+	// posts created within window
+	interval time.Duration
+	window   time.Duration
+	size     int
+}
+
+type commentsConfig struct {
+	// maxDepth is how deeply replies can nest, top level comments being 0
+	maxDepth int
+}
+
+type outboxConfig struct {
+	enabled      bool
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	// purger discards the data of messages dead lettered for longer than
+	// its retention
+	purger purgerConfig
+}
+
+type activationConfig struct {
+	resendLimiter ratelimiter.Config
+	reaper        reaperConfig
+}
+
+type reaperConfig struct {
+	enabled  bool
+	interval time.Duration
+	// gracePeriod is how long after their invitation expired never
+	// activated accounts are kept
+	gracePeriod time.Duration
 }
 
 type redisConfig struct {
@@ -66,16 +145,27 @@ type basicConfig struct {
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
+	secret     string
+	keys       string
+	activeKID  string
+	exp        time.Duration
+	refreshExp time.Duration
+	iss        string
 }
 
 type mailConfig struct {
-	sendGrid  sendGridConfig
-	mailTrap  mailTrapConfig
-	exp       time.Duration
-	fromEmail string
+	provider         string
+	sendGrid         sendGridConfig
+	mailTrap         mailTrapConfig
+	smtp             mailer.SMTPConfig
+	fileSink         fileSinkConfig
+	exp              time.Duration
+	passwordResetExp time.Duration
+	fromEmail        string
+}
+
+type fileSinkConfig struct {
+	dir string
 }
 
 type sendGridConfig struct {
@@ -101,9 +191,9 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5174")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -123,27 +213,78 @@ func (app *application) mount() http.Handler {
 			httpSwagger.URL(docsUrl), //The url pointing to API definition
 		))
 		r.Get("/health", app.healthCheckHandler)
+		r.Get("/.well-known/jwks.json", app.jwksHandler)
 		r.With(app.BasicAuthMiddleWare()).Get("/debug/vars", expvar.Handler().ServeHTTP)
+		r.With(app.AuthTokenMiddleware).Get("/search", app.searchHandler)
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+
+			r.Get("/trending", app.getTrendingTagsHandler)
+			r.Get("/{tag}/posts", app.getTagPostsHandler)
+		})
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
 			r.Post("/", app.createPostHandler)
 
+			// deleted posts can't be loaded by postsContextMiddleware
+			r.Put("/{postID}/restore", app.restorePostHandler)
+
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postsContextMiddleware)
 				r.Get("/", app.getPostHandler)
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Post("/comments", app.createCommentHandler)
+				r.Delete("/", app.checkPostOwnership(store.PermPostsDeleteAny, app.deletePostHandler))
+				r.Patch("/", app.checkPostOwnership(store.PermPostsUpdateAny, app.updatePostHandler))
+
+				r.Get("/revisions", app.listPostRevisionsHandler)
+				r.Get("/revisions/{version}", app.getPostRevisionDiffHandler)
+				r.Post("/reports", app.reportPostHandler)
+
+				r.Route("/reactions", func(r chi.Router) {
+					r.Get("/", app.listReactionsHandler)
+					r.Put("/{kind}", app.reactToPostHandler)
+					r.Delete("/{kind}", app.unreactToPostHandler)
+				})
+
+				r.Route("/comments", func(r chi.Router) {
+					r.Get("/", app.listCommentsHandler)
+					r.Post("/", app.createCommentHandler)
+
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.Use(app.commentsContextMiddleware)
+						r.Patch("/", app.checkCommentOwnership(store.PermCommentsModerate, app.updateCommentHandler))
+						r.Delete("/", app.checkCommentOwnership(store.PermCommentsModerate, app.deleteCommentHandler))
+						r.Post("/reports", app.reportCommentHandler)
+					})
+				})
 			})
 		})
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
+			r.Post("/activation/resend", app.resendActivationHandler)
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+
+				r.Patch("/", app.updateAccountHandler)
+				r.Get("/follow-requests", app.listFollowRequestsHandler)
+				r.Put("/follow-requests/{requesterID}/approve", app.approveFollowRequestHandler)
+				r.Put("/follow-requests/{requesterID}/reject", app.rejectFollowRequestHandler)
+				r.Get("/blocks", app.listBlockedHandler)
+				r.Get("/mutes", app.listMutedHandler)
+			})
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
 				r.Get("/", app.getUserHandler)
+				r.Get("/posts", app.getUserPostsHandler)
+				r.Get("/followers", app.listFollowersHandler)
+				r.Get("/following", app.listFollowingHandler)
+				r.Get("/friends", app.listFriendsHandler)
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Put("/block", app.blockUserHandler)
+				r.Delete("/block", app.unblockUserHandler)
+				r.Put("/mute", app.muteUserHandler)
+				r.Delete("/mute", app.unmuteUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
@@ -152,10 +293,58 @@ func (app *application) mount() http.Handler {
 			})
 
 		})
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requirePermission(store.PermReportsReview))
+
+			r.Get("/reports", app.listReportsHandler)
+			r.Get("/reports/{reportID}", app.getReportHandler)
+			r.Put("/reports/{reportID}/assign", app.assignReportHandler)
+			r.Post("/reports/{reportID}/resolve", app.resolveReportHandler)
+			r.Get("/log", app.listModerationLogHandler)
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(store.PermOutboxManage))
+
+				r.Get("/outbox", app.listOutboxHandler)
+				r.Post("/outbox/{messageID}/retry", app.retryOutboxHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(store.PermRolesManage))
+
+				r.Get("/roles", app.listRolesHandler)
+				r.Post("/roles", app.createRoleHandler)
+				r.Put("/users/{userID}/role", app.updateUserRoleHandler)
+				r.Get("/users/{userID}/role-changes", app.listRoleChangesHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(store.PermUsersSuspend))
+
+				r.Get("/users/{userID}/suspensions", app.listSuspensionsHandler)
+				r.Post("/users/{userID}/suspensions", app.suspendUserHandler)
+				r.Delete("/users/{userID}/suspensions", app.liftSuspensionHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(store.PermAuditRead))
+
+				r.Get("/audit-events", app.listAuditEventsHandler)
+				r.Get("/audit-events/export", app.exportAuditEventsHandler)
+			})
+		})
 		// Public routes
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.With(app.AuthTokenMiddleware).Post("/logout", app.logoutHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Put("/password/reset", app.resetPasswordHandler)
 		})
 	})
 
@@ -176,6 +365,12 @@ func (app *application) run(mux http.Handler) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	// background jobs stop once the server has shut down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app.startBackgroundJobs(ctx)
+
 	shutdown := make(chan error)
 
 	go func() {
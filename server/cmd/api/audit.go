@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// auditEvent fills in e as an action taken while serving r, on behalf of the
+// current user unless e names an actor. Stores record it in the transaction
+// of the mutation it describes.
+func auditEvent(r *http.Request, e *store.AuditEvent) *store.AuditEvent {
+	if e.ActorID == nil {
+		if user := getUserFromContext(r); user != nil {
+			e.ActorID = &user.ID
+		}
+	}
+
+	e.IP = clientIP(r)
+	e.RequestID = middleware.GetReqID(r.Context())
+
+	return e
+}
+
+// audit records an action taken while serving r that doesn't go through a
+// store mutation of its own. The action has already happened by then so
+// failing to record it is logged rather than failing the request.
+func (app *application) audit(r *http.Request, e *store.AuditEvent) {
+	if err := app.store.Audit.Create(r.Context(), auditEvent(r, e)); err != nil {
+		app.logger.Errorw("failed to record audit event", "action", e.Action, "request_id", e.RequestID, "error", err.Error())
+	}
+}
+
+// clientIP is the client address as resolved by middleware.RealIP, without
+// the port it falls back to when no proxy header was set.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// listAuditEventsHandler godoc
+//
+//	@Summary		Lists audit events
+//	@Description	Lists security and moderation relevant actions, most recent first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			actor_id	query		int		false	"Only the actions of this user"
+//	@Param			target_type	query		string	false	"Target type (user, post, report, role)"
+//	@Param			target_id	query		int		false	"Target ID"
+//	@Param			action		query		string	false	"Action"
+//	@Param			from		query		string	false	"Only the actions at or after this time"
+//	@Param			to			query		string	false	"Only the actions before this time"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]store.AuditEvent
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit-events [get]
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.AuditQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	events, err := app.store.Audit.List(r.Context(), q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, events); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+var auditCSVHeader = []string{
+	"id", "created_at", "actor_id", "action", "target_type", "target_id", "ip", "request_id", "metadata",
+}
+
+// exportAuditEventsHandler godoc
+//
+//	@Summary		Exports audit events
+//	@Description	Downloads every audit event matching the filters, most recent first, as CSV or newline delimited JSON
+//	@Tags			admin
+//	@Produce		text/csv,application/x-ndjson
+//	@Param			format		query		string	false	"Format (csv, ndjson)"
+//	@Param			actor_id	query		int		false	"Only the actions of this user"
+//	@Param			target_type	query		string	false	"Target type (user, post, report, role)"
+//	@Param			target_id	query		int		false	"Target ID"
+//	@Param			action		query		string	false	"Action"
+//	@Param			from		query		string	false	"Only the actions at or after this time"
+//	@Param			to			query		string	false	"Only the actions before this time"
+//	@Success		200			{string}	string	"Audit events"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit-events/export [get]
+func (app *application) exportAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := store.AuditQuery{}.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	// exports aren't paginated
+	if err := Validate.StructExcept(q, "Limit", "Offset"); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var (
+		write func(*store.AuditEvent) error
+		flush func() error
+	)
+
+	filename := "audit-events-" + time.Now().UTC().Format("20060102T150405Z")
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		cw := csv.NewWriter(w)
+
+		write = func(e *store.AuditEvent) error {
+			metadata, err := json.Marshal(e.Metadata)
+			if err != nil {
+				return err
+			}
+
+			return cw.Write([]string{
+				strconv.FormatInt(e.ID, 10),
+				e.CreatedAt.UTC().Format(time.RFC3339),
+				formatOptionalID(e.ActorID),
+				e.Action,
+				e.TargetType,
+				formatOptionalID(e.TargetID),
+				e.IP,
+				e.RequestID,
+				string(metadata),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		w.WriteHeader(http.StatusOK)
+
+		if err := cw.Write(auditCSVHeader); err != nil {
+			app.logger.Errorw("audit export failed", "error", err.Error())
+			return
+		}
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+
+		write = func(e *store.AuditEvent) error {
+			return encoder.Encode(e)
+		}
+		flush = func() error {
+			return nil
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, filename))
+		w.WriteHeader(http.StatusOK)
+	default:
+		app.badRequestError(w, r, fmt.Errorf("unknown format %q, expected csv or ndjson", format))
+		return
+	}
+
+	// the status is already sent, errors past this point can only cut the
+	// download short
+	if err := app.store.Audit.Export(r.Context(), q, write); err != nil {
+		app.logger.Errorw("audit export failed", "error", err.Error())
+	}
+
+	if err := flush(); err != nil {
+		app.logger.Errorw("audit export failed", "error", err.Error())
+	}
+}
+
+func formatOptionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+
+	return strconv.FormatInt(*id, 10)
+}
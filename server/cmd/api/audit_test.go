@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testAuditStore holds a login by the admin and a failed login with an
+// unknown email, most recent first, along with the events recorded since.
+type testAuditStore struct {
+	*store.MockAuditStore
+	events   []store.AuditEvent
+	created  []store.AuditEvent
+	lastList store.AuditQuery
+}
+
+func newTestAuditStore() *testAuditStore {
+	admin := adminID
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return &testAuditStore{
+		events: []store.AuditEvent{
+			{ID: 2, ActorID: &admin, Action: store.AuditLogin, TargetType: store.AuditTargetUser, TargetID: &admin, IP: "203.0.113.1", RequestID: "req-2", Metadata: map[string]any{}, CreatedAt: createdAt},
+			{ID: 1, Action: store.AuditLoginFailed, IP: "203.0.113.2", Metadata: map[string]any{"email": "a,b@example.com"}, CreatedAt: createdAt},
+		},
+	}
+}
+
+func (s *testAuditStore) Create(ctx context.Context, e *store.AuditEvent) error {
+	s.created = append(s.created, *e)
+	return nil
+}
+
+func (s *testAuditStore) List(ctx context.Context, q store.AuditQuery) ([]store.AuditEvent, error) {
+	s.lastList = q
+	return s.events, nil
+}
+
+func (s *testAuditStore) Export(ctx context.Context, q store.AuditQuery, fn func(*store.AuditEvent) error) error {
+	for i := range s.events {
+		if err := fn(&s.events[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestAuditEvents(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testUserStore{}
+	audit := newTestAuditStore()
+	app.store.Audit = audit
+	mux := app.mount()
+
+	request := func(path string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, adminID))
+
+		return req
+	}
+
+	t.Run("should list audit events", func(t *testing.T) {
+		rr := executeRequest(request("/v1/admin/audit-events?actor_id=3&target_type=user&action=auth.login&limit=5&offset=10"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.AuditEvent `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 2 || body.Data[0].ID != 2 || *body.Data[0].ActorID != adminID {
+			t.Errorf("expected both events, got %+v", body.Data)
+		}
+
+		q := audit.lastList
+		if q.ActorID != adminID || q.TargetType != store.AuditTargetUser || q.Action != store.AuditLogin || q.Limit != 5 || q.Offset != 10 {
+			t.Errorf("expected the query to carry the filters, got %+v", q)
+		}
+	})
+
+	t.Run("should reject invalid queries", func(t *testing.T) {
+		tests := []struct {
+			name string
+			path string
+		}{
+			{"from after to", "/v1/admin/audit-events?from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z"},
+			{"malformed from", "/v1/admin/audit-events?from=yesterday"},
+			{"unknown target type", "/v1/admin/audit-events?target_type=comment"},
+			{"limit too large", "/v1/admin/audit-events?limit=101"},
+			{"unknown format", "/v1/admin/audit-events/export?format=xml"},
+			{"export from after to", "/v1/admin/audit-events/export?from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z"},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(tt.path), mux)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should export audit events as CSV", func(t *testing.T) {
+		rr := executeRequest(request("/v1/admin/audit-events/export"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if ct := rr.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("expected a CSV download, got %s", ct)
+		}
+
+		if cd := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.HasSuffix(cd, `.csv"`) {
+			t.Errorf("expected a CSV attachment, got %s", cd)
+		}
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := [][]string{
+			auditCSVHeader,
+			{"2", "2026-01-02T03:04:05Z", "3", "auth.login", "user", "3", "203.0.113.1", "req-2", "{}"},
+			{"1", "2026-01-02T03:04:05Z", "", "auth.login_failed", "", "", "203.0.113.2", "", `{"email":"a,b@example.com"}`},
+		}
+
+		if len(records) != len(expected) {
+			t.Fatalf("expected %d records, got %q", len(expected), records)
+		}
+
+		for i := range expected {
+			if strings.Join(records[i], "|") != strings.Join(expected[i], "|") {
+				t.Errorf("record %d: expected %q, got %q", i, expected[i], records[i])
+			}
+		}
+	})
+
+	t.Run("should export audit events as newline delimited JSON", func(t *testing.T) {
+		rr := executeRequest(request("/v1/admin/audit-events/export?format=ndjson"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("expected an NDJSON download, got %s", ct)
+		}
+
+		var exported []store.AuditEvent
+
+		scanner := bufio.NewScanner(rr.Body)
+		for scanner.Scan() {
+			var e store.AuditEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("expected a JSON event per line, got %s", scanner.Text())
+			}
+
+			exported = append(exported, e)
+		}
+
+		if len(exported) != 2 || exported[0].ID != 2 || exported[1].Metadata["email"] != "a,b@example.com" {
+			t.Errorf("expected both events, got %+v", exported)
+		}
+	})
+
+	t.Run("should audit logins refused to suspended users", func(t *testing.T) {
+		app.store.Users = &testSuspendableUserStore{}
+		app.store.Suspensions = &testSuspensionStore{
+			active: map[int64]*store.Suspension{readerID: {UserID: readerID, Kind: store.SuspensionKindBan}},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", strings.NewReader(`{"email": "reader@example.com", "password": "password"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		if len(audit.created) != 1 {
+			t.Fatalf("expected a single audit event, got %+v", audit.created)
+		}
+
+		if e := audit.created[0]; e.Action != store.AuditLoginFailed || *e.TargetID != readerID || e.Metadata["reason"] != "suspended" {
+			t.Errorf("expected a failed login of the reader, got %+v", e)
+		}
+	})
+}
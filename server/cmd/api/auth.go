@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -17,6 +18,8 @@ type RegisterUserPayload struct {
 	Username string `json:"username" validate:"required,max=100"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
+	// Language is the preferred language for emails, e.g. "fr" or "pt-BR"
+	Language string `json:"language" validate:"omitempty,bcp47_language_tag,max=35"`
 }
 
 type UserWithToken struct {
@@ -52,6 +55,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	user := &store.User{
 		Username: payload.Username,
 		Email:    payload.Email,
+		Language: payload.Language,
 		Role:     store.Role{Name: "user"},
 	}
 
@@ -65,11 +69,16 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	plainToken := uuid.New().String()
 
-	// store the user
-	hash := sha256.Sum256([]byte(plainToken))
-	hashToken := hex.EncodeToString(hash[:])
+	// the invitation email is queued with the user and delivered by the
+	// outbox dispatcher
+	mail, err := app.activationMail(user, plainToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
-	err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.config.mail.exp)
+	// store the user
+	err = app.store.Users.CreateAndInvite(ctx, user, hashToken(plainToken), app.config.mail.exp, mail)
 	if err != nil {
 		switch err {
 		case store.ErrDuplicateEmail:
@@ -87,33 +96,20 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Token: plainToken,
 	}
 
+	if err := app.jsonResponse(w, http.StatusCreated, userWithToken); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) activationMail(user *store.User, plainToken string) (*store.OutboxMessage, error) {
 	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, plainToken)
 
-	isProdEnv := app.config.env == "production"
 	vars := struct {
 		Username      string
 		ActivationURL string
 	}{Username: user.Username, ActivationURL: activationURL}
 
-	// send email
-	statusCode, err := app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv)
-	if err != nil {
-		app.logger.Errorw("error sending welcome email", "error", err)
-
-		// rollback using saga pattern
-		if err := app.store.Users.Delete(ctx, user.ID); err != nil {
-			app.logger.Errorw("error deleting user", "error", err)
-		}
-
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	app.logger.Infow("Email sent", "status code", statusCode)
-
-	if err := app.jsonResponse(w, http.StatusCreated, userWithToken); err != nil {
-		app.internalServerError(w, r, err)
-	}
+	return store.NewOutboxMessage(mailer.UserWelcomeTemplate, user.Language, user.Username, user.Email, vars)
 }
 
 type CreateUserTokenPayload struct {
@@ -129,7 +125,7 @@ type CreateUserTokenPayload struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
-//	@Success		200		{string}	string					"Token"
+//	@Success		200		{object}	AuthTokens				"Tokens"
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
 //	@Failure		500		{object}	error
@@ -152,6 +148,10 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
+			app.audit(r, &store.AuditEvent{
+				Action:   store.AuditLoginFailed,
+				Metadata: map[string]any{"email": payload.Email, "reason": "unknown email"},
+			})
 			app.unAuthorizedError(w, r, err)
 			return
 		default:
@@ -163,28 +163,217 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	// compare user password to hash password
 	err = user.Password.Compare(payload.Password)
 	if err != nil {
+		app.audit(r, &store.AuditEvent{
+			Action:     store.AuditLoginFailed,
+			TargetType: store.AuditTargetUser,
+			TargetID:   &user.ID,
+			Metadata:   map[string]any{"reason": "wrong password"},
+		})
 		app.unAuthorizedError(w, r, err)
 		return
 	}
 
-	// generate the token -> add claims
-	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": app.config.auth.token.iss,
-		"aud": app.config.auth.token.iss,
+	failed := &store.AuditEvent{
+		Action:     store.AuditLoginFailed,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+		Metadata:   map[string]any{"reason": "suspended"},
 	}
 
-	token, err := app.authenticator.GenerateToken(claims)
+	if !app.checkNotSuspended(w, r, user.ID, failed) {
+		return
+	}
+
+	tokens, err := app.issueTokens(r.Context(), user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	app.audit(r, &store.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     store.AuditLogin,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+	})
+
 	// send it the client
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// jwksHandler godoc
+//
+//	@Summary		Lists token verification keys
+//	@Description	Publishes the public keys used to sign access tokens as a JSON Web Key Set
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKSet
+//	@Router			/.well-known/jwks.json [get]
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := writeJSON(w, http.StatusOK, app.authenticator.JWKS()); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type AuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refreshes a token
+//	@Description	Exchanges a refresh token for a new access and refresh token pair
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{object}	AuthTokens			"Tokens"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	refreshToken := uuid.New().String()
+	session := &store.Session{
+		Token:  hashToken(refreshToken),
+		Expiry: time.Now().Add(app.config.auth.token.refreshExp),
+	}
+
+	err := app.store.Sessions.Rotate(r.Context(), hashToken(payload.RefreshToken), session)
+	if err != nil {
+		switch err {
+		case store.ErrTokenReused:
+			app.logger.Warnw("refresh token reuse detected, session family revoked")
+			app.audit(r, &store.AuditEvent{Action: store.AuditTokenReused})
+			app.unAuthorizedError(w, r, err)
+		case store.ErrNotFound, store.ErrSessionExpired, store.ErrSessionRevoked:
+			app.unAuthorizedError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, err := app.generateAccessToken(session.UserID, session.FamilyID)
+	if err != nil {
 		app.internalServerError(w, r, err)
+		return
 	}
+
+	app.audit(r, &store.AuditEvent{
+		ActorID:    &session.UserID,
+		Action:     store.AuditTokenRefreshed,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &session.UserID,
+	})
+
+	tokens := AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(app.config.auth.token.exp.Seconds()),
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// logoutHandler godoc
+//
+//	@Summary		Logs out
+//	@Description	Revokes the session the access token belongs to
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		204	{string}	string	"Logged out"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/authentication/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := getSessionIDFromContext(r)
+
+	if err := app.store.Sessions.RevokeFamily(r.Context(), sessionID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	app.audit(r, &store.AuditEvent{
+		Action:     store.AuditLogout,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokens starts a new session family for the user and returns its first
+// access and refresh token pair.
+func (app *application) issueTokens(ctx context.Context, userID int64) (*AuthTokens, error) {
+	refreshToken := uuid.New().String()
+
+	session := &store.Session{
+		FamilyID: uuid.New().String(),
+		UserID:   userID,
+		Token:    hashToken(refreshToken),
+		Expiry:   time.Now().Add(app.config.auth.token.refreshExp),
+	}
+
+	if err := app.store.Sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := app.generateAccessToken(userID, session.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(app.config.auth.token.exp.Seconds()),
+	}, nil
+}
+
+func (app *application) generateAccessToken(userID int64, sessionID string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"sid": sessionID,
+		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+
+	return app.authenticator.GenerateToken(claims)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testSessionStore keeps the sessions of a single family, the way the
+// database rotates and revokes them.
+type testSessionStore struct {
+	*store.MockSessionStore
+	active  map[string]bool
+	rotated map[string]bool
+	revoked map[string]bool
+}
+
+func newTestSessionStore(tokens ...string) *testSessionStore {
+	s := &testSessionStore{
+		active:  make(map[string]bool),
+		rotated: make(map[string]bool),
+		revoked: make(map[string]bool),
+	}
+
+	for _, token := range tokens {
+		s.active[hashToken(token)] = true
+	}
+
+	return s
+}
+
+func (s *testSessionStore) Rotate(ctx context.Context, token string, next *store.Session) error {
+	if !s.active[token] && !s.rotated[token] {
+		return store.ErrNotFound
+	}
+
+	if s.revoked["test-session"] {
+		return store.ErrSessionRevoked
+	}
+
+	if s.rotated[token] {
+		s.revoked["test-session"] = true
+		return store.ErrTokenReused
+	}
+
+	delete(s.active, token)
+	s.rotated[token] = true
+	s.active[next.Token] = true
+
+	next.FamilyID = "test-session"
+	next.UserID = authorID
+
+	return nil
+}
+
+func (s *testSessionStore) RevokeFamily(ctx context.Context, familyID string) error {
+	s.revoked[familyID] = true
+	return nil
+}
+
+func (s *testSessionStore) IsActive(ctx context.Context, familyID string) (bool, error) {
+	return !s.revoked[familyID], nil
+}
+
+func refresh(t *testing.T, mux http.Handler, refreshToken string) (*AuthTokens, int) {
+	t.Helper()
+
+	body := `{"refresh_token": "` + refreshToken + `"}`
+
+	req, err := http.NewRequest(http.MethodPost, "/v1/authentication/refresh", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := executeRequest(req, mux)
+
+	var res struct {
+		Data AuthTokens `json:"data"`
+	}
+	if rr.Code == http.StatusCreated {
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &res.Data, rr.Code
+}
+
+func TestRefreshToken(t *testing.T) {
+	cfg := config{
+		auth: authConfig{
+			token: tokenConfig{exp: time.Hour, refreshExp: time.Hour},
+		},
+	}
+
+	t.Run("should rotate the refresh token", func(t *testing.T) {
+		app := newTestApplication(t, cfg)
+		app.store.Sessions = newTestSessionStore("first")
+		mux := app.mount()
+
+		tokens, code := refresh(t, mux, "first")
+		checkResponseCode(t, http.StatusCreated, code)
+
+		if tokens.RefreshToken == "" || tokens.RefreshToken == "first" {
+			t.Fatalf("expected a new refresh token, got %q", tokens.RefreshToken)
+		}
+
+		if tokens.AccessToken == "" {
+			t.Fatal("expected an access token")
+		}
+
+		// the rotated token keeps the family going
+		_, code = refresh(t, mux, tokens.RefreshToken)
+		checkResponseCode(t, http.StatusCreated, code)
+	})
+
+	t.Run("should revoke the family when a rotated token is reused", func(t *testing.T) {
+		app := newTestApplication(t, cfg)
+		sessions := newTestSessionStore("first")
+		app.store.Sessions = sessions
+		mux := app.mount()
+
+		tokens, code := refresh(t, mux, "first")
+		checkResponseCode(t, http.StatusCreated, code)
+
+		_, code = refresh(t, mux, "first")
+		checkResponseCode(t, http.StatusUnauthorized, code)
+
+		if !sessions.revoked["test-session"] {
+			t.Fatal("expected the session family to be revoked")
+		}
+
+		// the token issued by the legitimate rotation is revoked along with it
+		_, code = refresh(t, mux, tokens.RefreshToken)
+		checkResponseCode(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("should reject unknown refresh tokens", func(t *testing.T) {
+		app := newTestApplication(t, cfg)
+		app.store.Sessions = newTestSessionStore()
+		mux := app.mount()
+
+		_, code := refresh(t, mux, "unknown")
+		checkResponseCode(t, http.StatusUnauthorized, code)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	app := newTestApplication(t, config{})
+	sessions := newTestSessionStore()
+	app.store.Sessions = sessions
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should revoke the session of the access token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if !sessions.revoked["test-session"] {
+			t.Fatal("expected the session to be revoked")
+		}
+	})
+
+	t.Run("should reject access tokens of a revoked session", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+}
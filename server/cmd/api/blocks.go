@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// blockUserHandler godoc
+//
+//	@Summary		Blocks a user
+//	@Description	Blocks a user, removing follows in both directions and hiding each other's content
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User blocked"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateUserRelation(w, r, app.store.Blocks.Block)
+}
+
+// unblockUserHandler godoc
+//
+//	@Summary		Unblocks a user
+//	@Description	Unblocks a user. Follows removed by the block aren't restored.
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unblocked"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [delete]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateUserRelation(w, r, app.store.Blocks.Unblock)
+}
+
+// muteUserHandler godoc
+//
+//	@Summary		Mutes a user
+//	@Description	Hides a user's posts from the current user's feed
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User muted"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [put]
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateUserRelation(w, r, app.store.Blocks.Mute)
+}
+
+// unmuteUserHandler godoc
+//
+//	@Summary		Unmutes a user
+//	@Description	Shows a muted user's posts in the current user's feed again
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unmuted"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [delete]
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateUserRelation(w, r, app.store.Blocks.Unmute)
+}
+
+func (app *application) updateUserRelation(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, userID, otherID int64) error) {
+	otherID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if otherID == user.ID {
+		app.badRequestError(w, r, fmt.Errorf("users can't block or mute themselves"))
+		return
+	}
+
+	if err := update(r.Context(), user.ID, otherID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listBlockedHandler godoc
+//
+//	@Summary		Lists blocked users
+//	@Description	Lists the users the current user has blocked, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.BlockedUser
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/blocks [get]
+func (app *application) listBlockedHandler(w http.ResponseWriter, r *http.Request) {
+	app.listUserRelations(w, r, app.store.Blocks.GetBlocked)
+}
+
+// listMutedHandler godoc
+//
+//	@Summary		Lists muted users
+//	@Description	Lists the users the current user has muted, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.BlockedUser
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mutes [get]
+func (app *application) listMutedHandler(w http.ResponseWriter, r *http.Request) {
+	app.listUserRelations(w, r, app.store.Blocks.GetMuted)
+}
+
+func (app *application) listUserRelations(w http.ResponseWriter, r *http.Request, list func(ctx context.Context, userID int64, q store.ConnectionsQuery) ([]store.BlockedUser, error)) {
+	q := store.ConnectionsQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	users, err := list(r.Context(), getUserFromContext(r).ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// checkNotBlocked responds with a forbidden error and returns false when the
+// current user and otherID have blocked one another.
+func (app *application) checkNotBlocked(w http.ResponseWriter, r *http.Request, otherID int64) bool {
+	blocked, err := app.store.Blocks.IsBlocked(r.Context(), getUserFromContext(r).ID, otherID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	if blocked {
+		app.forbiddenError(w, r, fmt.Errorf("user %d is blocked", otherID))
+		return false
+	}
+
+	return true
+}
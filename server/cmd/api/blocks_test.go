@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testRelationStore records blocks and mutes. The author and the moderator
+// have blocked one another, user 99 doesn't exist.
+type testRelationStore struct {
+	*store.MockBlockStore
+	blocked []int64
+	muted   []int64
+}
+
+func (s *testRelationStore) Block(ctx context.Context, userID, blockedID int64) error {
+	if blockedID == 99 {
+		return store.ErrNotFound
+	}
+
+	s.blocked = append(s.blocked, blockedID)
+	return nil
+}
+
+func (s *testRelationStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	s.blocked = nil
+	return nil
+}
+
+func (s *testRelationStore) Mute(ctx context.Context, userID, mutedID int64) error {
+	s.muted = append(s.muted, mutedID)
+	return nil
+}
+
+func (s *testRelationStore) Unmute(ctx context.Context, userID, mutedID int64) error {
+	s.muted = nil
+	return nil
+}
+
+func (s *testRelationStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	pair := [2]int64{min(userID, otherID), max(userID, otherID)}
+	return pair == [2]int64{authorID, moderatorID}, nil
+}
+
+func (s *testRelationStore) GetBlocked(ctx context.Context, userID int64, q store.ConnectionsQuery) ([]store.BlockedUser, error) {
+	users := []store.BlockedUser{}
+	for _, id := range s.blocked {
+		users = append(users, store.BlockedUser{ID: id})
+	}
+
+	return users, nil
+}
+
+func TestBlocks(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Posts = &testPostStore{}
+	relations := &testRelationStore{}
+	app.store.Blocks = relations
+	mux := app.mount()
+
+	request := func(userID int64, method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, userID))
+
+		return req
+	}
+
+	t.Run("should block and list blocked users", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPut, "/v1/users/2/block", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		rr = executeRequest(request(readerID, http.MethodGet, "/v1/users/me/blocks", ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.BlockedUser `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 1 || body.Data[0].ID != moderatorID {
+			t.Errorf("expected user %d to be blocked, got %+v", moderatorID, body.Data)
+		}
+	})
+
+	t.Run("should unblock users", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodDelete, "/v1/users/2/block", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if len(relations.blocked) != 0 {
+			t.Errorf("expected no blocked users, got %v", relations.blocked)
+		}
+	})
+
+	t.Run("should mute and unmute users", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPut, "/v1/users/1/mute", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if len(relations.muted) != 1 || relations.muted[0] != authorID {
+			t.Errorf("expected user %d to be muted, got %v", authorID, relations.muted)
+		}
+
+		rr = executeRequest(request(readerID, http.MethodDelete, "/v1/users/1/mute", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if len(relations.muted) != 0 {
+			t.Errorf("expected no muted users, got %v", relations.muted)
+		}
+	})
+
+	t.Run("should not block themselves", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPut, "/v1/users/4/block", ""), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should not block unknown users", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPut, "/v1/users/99/block", ""), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should stop blocked users from interacting", func(t *testing.T) {
+		rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/posts/1/comments", `{"content": "hello"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(request(moderatorID, http.MethodPut, "/v1/users/1/follow", ""), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should let other users interact", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPost, "/v1/posts/1/comments", `{"content": "hello"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		rr = executeRequest(request(readerID, http.MethodPut, "/v1/users/1/follow", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testThreadCommentStore holds a top level comment on post 1 (1), a reply to
+// it (2) and a comment on post 2 (3).
+type testThreadCommentStore struct {
+	*store.MockCommentStore
+	created *store.Comment
+}
+
+func (s *testThreadCommentStore) GetByID(ctx context.Context, commentID int64) (*store.Comment, error) {
+	switch commentID {
+	case 1:
+		return &store.Comment{ID: 1, PostID: 1, UserID: authorID}, nil
+	case 2:
+		return &store.Comment{ID: 2, PostID: 1, UserID: readerID, ParentID: new(int64), Depth: 1}, nil
+	case 3:
+		return &store.Comment{ID: 3, PostID: 2, UserID: authorID}, nil
+	default:
+		return nil, store.ErrNotFound
+	}
+}
+
+func (s *testThreadCommentStore) Create(ctx context.Context, comment *store.Comment) error {
+	comment.ID = 4
+	s.created = comment
+	return nil
+}
+
+// ListByPostID returns as many comments as asked for, up to 3.
+func (s *testThreadCommentStore) ListByPostID(ctx context.Context, postID, viewerID int64, q store.CommentsQuery) ([]store.Comment, error) {
+	comments := []store.Comment{}
+	for i := 1; i <= min(q.Limit, 3); i++ {
+		comments = append(comments, store.Comment{
+			ID:        int64(i),
+			PostID:    postID,
+			CreatedAt: fmt.Sprintf("2024-01-0%dT00:00:00Z", i),
+		})
+	}
+
+	return comments, nil
+}
+
+func TestComments(t *testing.T) {
+	cfg := config{
+		comments: commentsConfig{maxDepth: 1},
+	}
+
+	app := newTestApplication(t, cfg)
+	app.store.Posts = &testPostStore{}
+	comments := &testThreadCommentStore{}
+	app.store.Comments = comments
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	request := func(method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return req
+	}
+
+	t.Run("should create a comment", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPost, "/v1/posts/1/comments", `{"content": "hello"}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if comments.created == nil || comments.created.UserID != readerID || comments.created.PostID != 1 {
+			t.Errorf("expected a comment by user %d on post 1, got %+v", readerID, comments.created)
+		}
+	})
+
+	t.Run("should reply to a comment", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPost, "/v1/posts/1/comments", `{"content": "hello", "parent_id": 1}`), mux)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if parent := comments.created.ParentID; parent == nil || *parent != 1 || comments.created.Depth != 1 {
+			t.Errorf("expected a reply to comment 1, got %+v", comments.created)
+		}
+	})
+
+	t.Run("should reject invalid replies", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"missing content", `{"content": ""}`},
+			{"unknown parent", `{"content": "hello", "parent_id": 9}`},
+			{"parent on another post", `{"content": "hello", "parent_id": 3}`},
+			{"nested too deep", `{"content": "hello", "parent_id": 2}`},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(http.MethodPost, "/v1/posts/1/comments", tt.body), mux)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should page comments with a cursor", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodGet, "/v1/posts/1/comments?limit=2", ""), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var page struct {
+			Data       []store.Comment `json:"data"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page.Data) != 2 {
+			t.Fatalf("expected 2 comments, got %d", len(page.Data))
+		}
+
+		cursor, err := store.DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("expected a valid next cursor, got %q", page.NextCursor)
+		}
+
+		if cursor.ID != 2 {
+			t.Errorf("expected the cursor to point after comment 2, got %d", cursor.ID)
+		}
+
+		if link := rr.Header().Get("Link"); !strings.Contains(link, "cursor="+page.NextCursor) {
+			t.Errorf("expected a Link header to the next page, got %q", link)
+		}
+	})
+
+	t.Run("should not return a cursor on the last page", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodGet, "/v1/posts/1/comments?limit=5", ""), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if strings.Contains(rr.Body.String(), "next_cursor") || rr.Header().Get("Link") != "" {
+			t.Errorf("expected no next page, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should reject invalid cursors", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodGet, "/v1/posts/1/comments?cursor=nope", ""), mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should only reach comments through their post", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPatch, "/v1/posts/1/comments/3", `{"content": "edited"}`), mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should let authors edit and delete their comments", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPatch, "/v1/posts/1/comments/2", `{"content": "edited"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		rr = executeRequest(request(http.MethodDelete, "/v1/posts/1/comments/2", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
 )
 
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
@@ -44,6 +47,19 @@ func (app *application) forbiddenError(w http.ResponseWriter, r *http.Request, e
 	writeJSONError(w, http.StatusForbidden, "forbidden")
 }
 
+// suspendedError tells users their account is suspended, and until when,
+// so they aren't mistaken into thinking their credentials are wrong.
+func (app *application) suspendedError(w http.ResponseWriter, r *http.Request, s *store.Suspension) {
+	app.logger.Warnw("suspended user", "method", r.Method, "path", r.URL.Path, "user_id", s.UserID, "suspension_id", s.ID)
+
+	message := "account " + s.Kind + " in effect until lifted"
+	if s.ExpiresAt != nil {
+		message = "account " + s.Kind + " in effect until " + s.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	writeJSONError(w, http.StatusForbidden, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfrer string) {
 	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path)
 
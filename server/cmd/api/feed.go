@@ -1,7 +1,11 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/iykeevans/go-social/server/internal/store"
 )
@@ -9,7 +13,7 @@ import (
 // getUserFeedHandler godoc
 //
 //	@Summary		Fetches the user feed
-//	@Description	Fetches the user feed
+//	@Description	Fetches the user's posts and the posts of the users they follow. Pass the returned next_cursor to fetch the following page.
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
@@ -17,6 +21,7 @@ import (
 //	@Param			until	query		string	false	"Until"
 //	@Param			limit	query		int		false	"Limit"
 //	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
 //	@Param			sort	query		string	false	"Sort"
 //	@Param			tags	query		string	false	"Tags"
 //	@Param			search	query		string	false	"Search"
@@ -26,34 +31,117 @@ import (
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
-	// pagination, filters
-	fq := store.PaginatedFeedQuery{
-		Limit:  20,
-		Offset: 0,
-		Sort:   "desc",
+	fq, err := parseFeedQuery(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
 	}
 
-	fq, err := fq.Parse(r)
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	feeds, err := app.store.Posts.GetUserFeed(ctx, user.ID, fq)
+
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, feeds, nextFeedCursor(feeds, fq)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getUserPostsHandler godoc
+//
+//	@Summary		Fetches a user's posts
+//	@Description	Fetches the posts written by a user, with the same filters and pagination as the feed
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			since	query		string	false	"Since"
+//	@Param			until	query		string	false	"Until"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort"
+//	@Param			tags	query		string	false	"Tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	authorID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
 	if err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
-	if err := Validate.Struct(fq); err != nil {
+	fq, err := parseFeedQuery(r)
+	if err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
 	ctx := r.Context()
 
-	feeds, err := app.store.Posts.GetUserFeed(ctx, int64(42), fq)
+	author, err := app.getUser(ctx, authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkContentVisibility(w, r, author) {
+		return
+	}
 
+	posts, err := app.store.Posts.GetUserPosts(ctx, author.ID, getUserFromContext(r).ID, fq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feeds); err != nil {
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, posts, nextFeedCursor(posts, fq)); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+func parseFeedQuery(r *http.Request) (store.PaginatedFeedQuery, error) {
+	// pagination, filters
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		return fq, err
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		return fq, err
+	}
+
+	return fq, nil
+}
+
+// nextFeedCursor points after the last post of a full page, there being no
+// next page otherwise.
+func nextFeedCursor(posts []store.PostWithMetadata, fq store.PaginatedFeedQuery) string {
+	if len(posts) < fq.Limit {
+		return ""
+	}
+
+	last := posts[len(posts)-1]
+
+	return store.EncodeCursor(last.CreatedAt, last.ID)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testFeedPostStore returns as many posts as asked for, up to 3, and keeps
+// the last feed query it was given.
+type testFeedPostStore struct {
+	*store.MockPostStore
+	query store.PaginatedFeedQuery
+}
+
+func (s *testFeedPostStore) GetUserFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	s.query = fq
+
+	posts := []store.PostWithMetadata{}
+	for i := 1; i <= min(fq.Limit, 3); i++ {
+		posts = append(posts, store.PostWithMetadata{
+			Post: store.Post{ID: int64(i), CreatedAt: fmt.Sprintf("2024-01-0%dT00:00:00Z", i)},
+		})
+	}
+
+	return posts, nil
+}
+
+func (s *testFeedPostStore) GetUserPosts(ctx context.Context, authorID, viewerID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	s.query = fq
+
+	return []store.PostWithMetadata{
+		{Post: store.Post{ID: 1, UserID: authorID, CreatedAt: "2024-01-01T00:00:00Z"}},
+	}, nil
+}
+
+type feedPage struct {
+	Data       []store.PostWithMetadata `json:"data"`
+	NextCursor string                   `json:"next_cursor"`
+}
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t, config{})
+	posts := &testFeedPostStore{}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	getFeed := func(query string) (*feedPage, int) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		page := &feedPage{}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(page); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return page, rr.Code
+	}
+
+	t.Run("should return a cursor to the next page", func(t *testing.T) {
+		page, code := getFeed("?limit=2")
+		checkResponseCode(t, http.StatusOK, code)
+
+		cursor, err := store.DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("expected a valid next cursor, got %q", page.NextCursor)
+		}
+
+		if cursor.ID != 2 {
+			t.Errorf("expected the cursor to point after post 2, got %d", cursor.ID)
+		}
+
+		_, code = getFeed("?limit=2&cursor=" + page.NextCursor)
+		checkResponseCode(t, http.StatusOK, code)
+
+		if posts.query.Cursor != page.NextCursor {
+			t.Errorf("expected the cursor to be passed to the store, got %q", posts.query.Cursor)
+		}
+	})
+
+	t.Run("should not return a cursor on the last page", func(t *testing.T) {
+		page, code := getFeed("?limit=5")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if page.NextCursor != "" {
+			t.Errorf("expected no next cursor, got %q", page.NextCursor)
+		}
+	})
+
+	t.Run("should reject invalid cursors", func(t *testing.T) {
+		tests := []string{
+			"?cursor=nope",
+			"?cursor=" + store.EncodeCursor("yesterday", 1),
+			"?offset=20&cursor=" + store.EncodeCursor("2024-01-01T00:00:00Z", 1),
+		}
+
+		for _, query := range tests {
+			if _, code := getFeed(query); code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", query, http.StatusBadRequest, code)
+			}
+		}
+	})
+
+	t.Run("should filter the feed by date", func(t *testing.T) {
+		_, code := getFeed("?since=2024-01-01T00:00:00Z&until=2024-02-01%2000:00:00")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if posts.query.Since != "2024-01-01T00:00:00Z" || posts.query.Until != "2024-02-01T00:00:00Z" {
+			t.Errorf("expected the dates to be passed to the store, got %q and %q", posts.query.Since, posts.query.Until)
+		}
+	})
+
+	t.Run("should reject invalid date filters", func(t *testing.T) {
+		tests := []string{
+			"?since=yesterday",
+			"?until=2024-13-01T00:00:00Z",
+			"?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z",
+		}
+
+		for _, query := range tests {
+			if _, code := getFeed(query); code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", query, http.StatusBadRequest, code)
+			}
+		}
+	})
+
+	t.Run("should keep the filters in the next page link", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed?limit=2&offset=0&tags=go&since=2024-01-01T00:00:00Z", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		link := rr.Header().Get("Link")
+		if !strings.Contains(link, "tags=go") || !strings.Contains(link, "since=") || !strings.Contains(link, "cursor=") || strings.Contains(link, "offset=") {
+			t.Errorf("expected a cursor link keeping the filters, got %q", link)
+		}
+	})
+}
+
+func TestGetUserPosts(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testProfileUserStore{}
+	posts := &testFeedPostStore{}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	getPosts := func(path string) (*feedPage, int) {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		page := &feedPage{}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(page); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return page, rr.Code
+	}
+
+	t.Run("should list the posts of a user", func(t *testing.T) {
+		page, code := getPosts("/v1/users/1/posts?limit=1&tags=go")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if len(page.Data) != 1 || page.Data[0].UserID != authorID {
+			t.Errorf("expected the posts of user %d, got %+v", authorID, page.Data)
+		}
+
+		if page.NextCursor == "" {
+			t.Error("expected a cursor to the next page")
+		}
+
+		if len(posts.query.Tags) != 1 || posts.query.Tags[0] != "go" {
+			t.Errorf("expected the feed filters to apply, got %v", posts.query.Tags)
+		}
+	})
+
+	t.Run("should not list the posts of unknown users", func(t *testing.T) {
+		_, code := getPosts("/v1/users/99/posts")
+		checkResponseCode(t, http.StatusNotFound, code)
+	})
+}
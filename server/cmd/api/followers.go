@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type connectionsLister func(ctx context.Context, userID, viewerID int64, q store.ConnectionsQuery) ([]store.Connection, error)
+
+// listFollowersHandler godoc
+//
+//	@Summary		Lists a user's followers
+//	@Description	Lists who follows a user, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.Connection
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/followers [get]
+func (app *application) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listConnections(w, r, app.store.Followers.GetFollowers)
+}
+
+// listFollowingHandler godoc
+//
+//	@Summary		Lists whom a user follows
+//	@Description	Lists the users a user follows, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.Connection
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/following [get]
+func (app *application) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listConnections(w, r, app.store.Followers.GetFollowing)
+}
+
+// listFriendsHandler godoc
+//
+//	@Summary		Lists a user's friends
+//	@Description	Lists the users who follow a user and are followed back
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.Connection
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/friends [get]
+func (app *application) listFriendsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listConnections(w, r, app.store.Followers.GetFriends)
+}
+
+func (app *application) listConnections(w http.ResponseWriter, r *http.Request, list connectionsLister) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	q := store.ConnectionsQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err = q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkContentVisibility(w, r, user) {
+		return
+	}
+
+	connections, err := list(ctx, user.ID, getUserFromContext(r).ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, connections); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// listFollowRequestsHandler godoc
+//
+//	@Summary		Lists pending follow requests
+//	@Description	Lists the requests to follow the current user, oldest first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.FollowRequest
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) listFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.ConnectionsQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	requests, err := app.store.Followers.GetFollowRequests(r.Context(), getUserFromContext(r).ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, requests); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// approveFollowRequestHandler godoc
+//
+//	@Summary		Approves a follow request
+//	@Description	Lets the requester follow the current user
+//	@Tags			users
+//	@Produce		json
+//	@Param			requesterID	path		int		true	"Requester ID"
+//	@Success		204			{string}	string	"Request approved"
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{requesterID}/approve [put]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveFollowRequest(w, r, app.store.Followers.ApproveFollowRequest)
+}
+
+// rejectFollowRequestHandler godoc
+//
+//	@Summary		Rejects a follow request
+//	@Description	Discards a request to follow the current user
+//	@Tags			users
+//	@Produce		json
+//	@Param			requesterID	path		int		true	"Requester ID"
+//	@Success		204			{string}	string	"Request rejected"
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{requesterID}/reject [put]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.resolveFollowRequest(w, r, app.store.Followers.RejectFollowRequest)
+}
+
+func (app *application) resolveFollowRequest(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, userID, requesterID int64) error) {
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "requesterID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := resolve(r.Context(), getUserFromContext(r).ID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testConnectionsFollowerStore records which listing was asked for, and for
+// whom.
+type testConnectionsFollowerStore struct {
+	*store.MockFollowerStore
+	listed   string
+	userID   int64
+	viewerID int64
+}
+
+func (s *testConnectionsFollowerStore) list(name string, userID, viewerID int64) ([]store.Connection, error) {
+	s.listed, s.userID, s.viewerID = name, userID, viewerID
+	return []store.Connection{}, nil
+}
+
+func (s *testConnectionsFollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, q store.ConnectionsQuery) ([]store.Connection, error) {
+	return s.list("followers", userID, viewerID)
+}
+
+func (s *testConnectionsFollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, q store.ConnectionsQuery) ([]store.Connection, error) {
+	return s.list("following", userID, viewerID)
+}
+
+func (s *testConnectionsFollowerStore) GetFriends(ctx context.Context, userID, viewerID int64, q store.ConnectionsQuery) ([]store.Connection, error) {
+	return s.list("friends", userID, viewerID)
+}
+
+func TestListConnections(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testProfileUserStore{}
+	followers := &testConnectionsFollowerStore{}
+	app.store.Followers = followers
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	tests := []struct {
+		path     string
+		expected int
+		listed   string
+	}{
+		{"/v1/users/1/followers", http.StatusOK, "followers"},
+		{"/v1/users/1/following?limit=10&offset=10", http.StatusOK, "following"},
+		{"/v1/users/1/friends", http.StatusOK, "friends"},
+		{"/v1/users/1/followers?limit=500", http.StatusBadRequest, ""},
+		{"/v1/users/1/following?offset=-1", http.StatusBadRequest, ""},
+		{"/v1/users/99/friends", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			followers.listed = ""
+
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+
+			if followers.listed != tt.listed {
+				t.Errorf("expected the %q listing, got %q", tt.listed, followers.listed)
+			}
+
+			// relationships are relative to the current user
+			if tt.listed != "" && (followers.userID != authorID || followers.viewerID != readerID) {
+				t.Errorf("expected user %d listed for %d, got %d for %d", authorID, readerID, followers.userID, followers.viewerID)
+			}
+		})
+	}
+}
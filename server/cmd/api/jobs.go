@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+func (app *application) startBackgroundJobs(ctx context.Context) {
+	if reaper := app.config.activation.reaper; reaper.enabled {
+		go app.runPeriodically(ctx, "inactive user reaper", reaper.interval, app.reapInactiveUsers)
+	}
+
+	if outbox := app.config.outbox; outbox.enabled {
+		go app.runPeriodically(ctx, "outbox dispatcher", outbox.pollInterval, app.dispatchOutbox)
+	}
+
+	if purger := app.config.outbox.purger; purger.enabled {
+		go app.runPeriodically(ctx, "dead outbox data purger", purger.interval, app.purgeDeadOutboxData)
+	}
+
+	if trending := app.config.tags.trending; trending.enabled {
+		go app.runPeriodically(ctx, "trending tags", trending.interval, app.refreshTrendingTags)
+	}
+
+	if purger := app.config.posts.purger; purger.enabled {
+		go app.runPeriodically(ctx, "deleted posts purger", purger.interval, app.purgeDeletedPosts)
+	}
+
+	if lifter := app.config.suspensions.lifter; lifter.enabled {
+		go app.runPeriodically(ctx, "suspension lifter", lifter.interval, app.liftExpiredSuspensions)
+	}
+}
+
+// runPeriodically calls job every interval until ctx is cancelled. Failures
+// are logged and retried on the next tick.
+func (app *application) runPeriodically(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			app.logger.Errorw("background job failed", "job", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) reapInactiveUsers(ctx context.Context) error {
+	cutoff := time.Now().Add(-app.config.activation.reaper.gracePeriod)
+
+	deleted, err := app.store.Users.DeleteUnactivated(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		app.logger.Infow("purged never activated users", "count", deleted, "invitation_expired_before", cutoff)
+	}
+
+	return nil
+}
+
+func (app *application) refreshTrendingTags(ctx context.Context) error {
+	trending := app.config.tags.trending
+
+	return app.store.Tags.RefreshTrending(ctx, trending.window, trending.size)
+}
+
+func (app *application) purgeDeletedPosts(ctx context.Context) error {
+	cutoff := time.Now().Add(-app.config.posts.purger.retention)
+
+	purged, err := app.store.Posts.PurgeDeleted(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		app.logger.Infow("purged deleted posts", "count", purged, "deleted_before", cutoff)
+	}
+
+	return nil
+}
+
+func (app *application) purgeDeadOutboxData(ctx context.Context) error {
+	cutoff := time.Now().Add(-app.config.outbox.purger.retention)
+
+	purged, err := app.store.Outbox.PurgeDeadData(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		app.logger.Infow("purged dead lettered outbox data", "count", purged, "dead_before", cutoff)
+	}
+
+	return nil
+}
+
+func (app *application) liftExpiredSuspensions(ctx context.Context) error {
+	lifted, err := app.store.Suspensions.LiftExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if lifted > 0 {
+		app.logger.Infow("lifted expired suspensions", "count", lifted)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type testReaperUserStore struct {
+	*store.MockUserStore
+	expiredBefore time.Time
+}
+
+func (s *testReaperUserStore) DeleteUnactivated(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	s.expiredBefore = expiredBefore
+	return 1, nil
+}
+
+func TestReapInactiveUsers(t *testing.T) {
+	cfg := config{
+		activation: activationConfig{
+			reaper: reaperConfig{gracePeriod: time.Hour * 24},
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	users := &testReaperUserStore{}
+	app.store.Users = users
+
+	if err := app.reapInactiveUsers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// accounts are kept for the grace period after their invitation expired
+	expected := time.Now().Add(-cfg.activation.reaper.gracePeriod)
+	if d := expected.Sub(users.expiredBefore); d < 0 || d > time.Minute {
+		t.Errorf("expected invitations expired before %v, got %v", expected, users.expiredBefore)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -45,3 +46,25 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 
 	return writeJSON(w, status, &envelope{Data: data})
 }
+
+// paginatedJSONResponse writes a page of results along with the cursor of the
+// next page, which is also advertised through a Link header. nextCursor is
+// empty on the last page.
+func (app *application) paginatedJSONResponse(w http.ResponseWriter, r *http.Request, status int, data any, nextCursor string) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+	}
+
+	if nextCursor != "" {
+		next := *r.URL
+		qs := next.Query()
+		qs.Set("cursor", nextCursor)
+		qs.Del("offset")
+		next.RawQuery = qs.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: nextCursor})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"expvar"
+	"fmt"
 	"runtime"
 	"time"
 
@@ -55,14 +56,27 @@ func main() {
 		},
 		env: env.GetString("ENV", "production"),
 		mail: mailConfig{
-			exp:       time.Hour * 24 * 3, // 3days
-			fromEmail: env.GetString("FROM_EMAIL", ""),
+			provider:         env.GetString("MAIL_PROVIDER", "mailtrap"),
+			exp:              time.Hour * 24 * 3, // 3days
+			passwordResetExp: time.Hour,
+			fromEmail:        env.GetString("FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", ""),
 			},
 			mailTrap: mailTrapConfig{
 				apiKey: env.GetString("MAILTRAP_API_KEY", ""),
 			},
+			smtp: mailer.SMTPConfig{
+				Host:     env.GetString("SMTP_HOST", ""),
+				Port:     env.GetInt("SMTP_PORT", 587),
+				Username: env.GetString("SMTP_USERNAME", ""),
+				Password: env.GetString("SMTP_PASSWORD", ""),
+				TLSMode:  env.GetString("SMTP_TLS_MODE", mailer.TLSModeStartTLS),
+				Auth:     env.GetString("SMTP_AUTH", mailer.AuthPlain),
+			},
+			fileSink: fileSinkConfig{
+				dir: env.GetString("MAIL_FILE_SINK_DIR", "./tmp/mail"),
+			},
 		},
 		auth: authConfig{
 			basic: basicConfig{
@@ -70,9 +84,12 @@ func main() {
 				pass: env.GetString("AUTH_BASIC_PASSWORD", "admin"),
 			},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_TOKEN_SECRET", "example"),
-				exp:    time.Hour * 24 * 3, // 3 days
-				iss:    "go-social",
+				secret:     env.GetString("AUTH_TOKEN_SECRET", "example"),
+				keys:       env.GetString("AUTH_TOKEN_KEYS", ""),
+				activeKID:  env.GetString("AUTH_TOKEN_ACTIVE_KID", ""),
+				exp:        time.Minute * 15,
+				refreshExp: time.Hour * 24 * 7, // 7 days
+				iss:        "go-social",
 			},
 		},
 		rateLimiter: ratelimiter.Config{
@@ -80,6 +97,58 @@ func main() {
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		activation: activationConfig{
+			resendLimiter: ratelimiter.Config{
+				RequestsPerTimeFrame: env.GetInt("ACTIVATION_RESEND_LIMIT", 3),
+				TimeFrame:            time.Hour,
+				Enabled:              true,
+			},
+			reaper: reaperConfig{
+				enabled:     env.GetBool("INACTIVE_USER_REAPER_ENABLED", true),
+				interval:    env.GetDuration("INACTIVE_USER_REAPER_INTERVAL", time.Hour),
+				gracePeriod: env.GetDuration("INACTIVE_USER_GRACE_PERIOD", time.Hour*24*7), // 7 days
+			},
+		},
+		outbox: outboxConfig{
+			enabled:      env.GetBool("OUTBOX_DISPATCHER_ENABLED", true),
+			pollInterval: env.GetDuration("OUTBOX_POLL_INTERVAL", time.Second*5),
+			batchSize:    env.GetInt("OUTBOX_BATCH_SIZE", 20),
+			lease:        time.Minute,
+			maxAttempts:  env.GetInt("OUTBOX_MAX_ATTEMPTS", 8),
+			baseBackoff:  time.Second * 10,
+			maxBackoff:   time.Hour,
+			purger: purgerConfig{
+				enabled:   env.GetBool("OUTBOX_PURGER_ENABLED", true),
+				interval:  env.GetDuration("OUTBOX_PURGER_INTERVAL", time.Hour),
+				retention: env.GetDuration("OUTBOX_DEAD_RETENTION", time.Hour*24*30), // 30 days
+			},
+		},
+		comments: commentsConfig{
+			maxDepth: env.GetInt("COMMENTS_MAX_DEPTH", 5),
+		},
+		tags: tagsConfig{
+			trending: trendingConfig{
+				enabled:  env.GetBool("TRENDING_TAGS_ENABLED", true),
+				interval: env.GetDuration("TRENDING_TAGS_INTERVAL", time.Minute*10),
+				window:   env.GetDuration("TRENDING_TAGS_WINDOW", time.Hour*24),
+				size:     env.GetInt("TRENDING_TAGS_SIZE", 50),
+			},
+		},
+		posts: postsConfig{
+			restoreWindow: env.GetDuration("POSTS_RESTORE_WINDOW", time.Hour*24*7), // 7 days
+			purger: purgerConfig{
+				enabled:   env.GetBool("POSTS_PURGER_ENABLED", true),
+				interval:  env.GetDuration("POSTS_PURGER_INTERVAL", time.Hour),
+				retention: env.GetDuration("POSTS_RETENTION", time.Hour*24*30), // 30 days
+			},
+		},
+		suspensions: suspensionsConfig{
+			lifter: lifterConfig{
+				enabled:  env.GetBool("SUSPENSION_LIFTER_ENABLED", true),
+				interval: env.GetDuration("SUSPENSION_LIFTER_INTERVAL", time.Minute),
+			},
+		},
+		permissionsTTL: env.GetDuration("PERMISSIONS_CACHE_TTL", time.Minute),
 	}
 
 	// logger
@@ -110,16 +179,23 @@ func main() {
 
 	// Rate Limiter
 	rateLimiter := ratelimiter.NewFixedWindowLimiter(cfg.rateLimiter.RequestsPerTimeFrame, cfg.rateLimiter.TimeFrame)
+	activationLimiter := ratelimiter.NewFixedWindowLimiter(
+		cfg.activation.resendLimiter.RequestsPerTimeFrame,
+		cfg.activation.resendLimiter.TimeFrame,
+	)
 
 	store := store.NewStorage(db)
 	cacheStorage := cache.NewRedisStorage(rdb)
 
 	// Mailer
-	// mailer := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
-	mailtrap, err := mailer.NewMailTrapClient(cfg.mail.mailTrap.apiKey, cfg.mail.fromEmail)
-
-	jwtAuthenticator := auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
+	mailClient, err := newMailer(cfg.mail)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infow("mailer configured", "provider", cfg.mail.provider)
 
+	// Authenticator
+	jwtAuthenticator, err := newAuthenticator(cfg.auth.token)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -129,9 +205,12 @@ func main() {
 		store:         store,
 		cacheStorage:  cacheStorage,
 		logger:        logger,
-		mailer:        mailtrap,
+		mailer:        mailClient,
 		authenticator: jwtAuthenticator,
 		rateLimiter:   rateLimiter,
+
+		activationLimiter: activationLimiter,
+		permissions:       newPermissionCache(store.Roles.GetPermissions, cfg.permissionsTTL),
 	}
 
 	// Metrics collected
@@ -147,3 +226,34 @@ func main() {
 
 	logger.Fatal(app.run(mux))
 }
+
+// newAuthenticator signs with the asymmetric keys listed in AUTH_TOKEN_KEYS
+// when set and falls back to the shared AUTH_TOKEN_SECRET otherwise.
+func newAuthenticator(cfg tokenConfig) (*auth.JWTAuthenticator, error) {
+	if cfg.keys == "" {
+		return auth.NewJWTAuthenticator(cfg.secret, cfg.iss, cfg.iss), nil
+	}
+
+	keys, err := auth.LoadKeys(cfg.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewJWTAuthenticatorWithKeys(keys, cfg.activeKID, cfg.iss, cfg.iss)
+}
+
+// newMailer picks the mail backend named by MAIL_PROVIDER.
+func newMailer(cfg mailConfig) (mailer.Client, error) {
+	switch cfg.provider {
+	case "sendgrid":
+		return mailer.NewSendgrid(cfg.sendGrid.apiKey, cfg.fromEmail), nil
+	case "mailtrap":
+		return mailer.NewMailTrapClient(cfg.mailTrap.apiKey, cfg.fromEmail)
+	case "smtp":
+		return mailer.NewSMTPClient(cfg.smtp, cfg.fromEmail)
+	case "file":
+		return mailer.NewFileSinkClient(cfg.fileSink.dir, cfg.fromEmail)
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", cfg.provider)
+	}
+}
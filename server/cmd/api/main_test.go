@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/mailer"
+)
+
+func TestNewMailer(t *testing.T) {
+	t.Run("should write emails to files with the file provider", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := mailConfig{
+			provider:  "file",
+			fileSink:  fileSinkConfig{dir: dir},
+			fromEmail: "noreply@example.com",
+		}
+
+		client, err := newMailer(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := map[string]any{
+			"Username":      "bob",
+			"ActivationURL": "http://localhost:5173/confirm/abc",
+		}
+
+		if _, err := client.Send(mailer.UserWelcomeTemplate, "en", "bob", "bob@example.com", data, true); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(files) != 1 {
+			t.Errorf("expected the email to be written to a file, got %v", files)
+		}
+	})
+
+	t.Run("should validate the smtp configuration", func(t *testing.T) {
+		cfg := mailConfig{provider: "smtp", fromEmail: "noreply@example.com"}
+
+		if _, err := newMailer(cfg); err == nil {
+			t.Error("expected an error for a missing smtp host")
+		}
+
+		cfg.smtp = mailer.SMTPConfig{Host: "localhost", Port: 25, TLSMode: mailer.TLSModeNone, Auth: mailer.AuthNone}
+
+		if _, err := newMailer(cfg); err != nil {
+			t.Errorf("expected a valid smtp configuration, got %v", err)
+		}
+	})
+
+	t.Run("should reject unknown providers", func(t *testing.T) {
+		if _, err := newMailer(mailConfig{provider: "carrier-pigeon"}); err == nil {
+			t.Error("expected an error for an unknown provider")
+		}
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -43,20 +44,52 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		sessionID, _ := claims["sid"].(string)
+		if sessionID == "" {
+			app.unAuthorizedError(w, r, fmt.Errorf("token is not bound to a session"))
+			return
+		}
+
 		ctx := r.Context()
 
+		active, err := app.store.Sessions.IsActive(ctx, sessionID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !active {
+			app.unAuthorizedError(w, r, store.ErrSessionRevoked)
+			return
+		}
+
 		user, err := app.getUser(ctx, userID)
 		if err != nil {
 			app.unAuthorizedError(w, r, err)
 			return
 		}
 
+		if !app.checkNotSuspended(w, r, user.ID, nil) {
+			return
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, sessionCtx, sessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+type sessionKey string
+
+const sessionCtx sessionKey = "session"
+
+func getSessionIDFromContext(r *http.Request) string {
+	sessionID, _ := r.Context().Value(sessionCtx).(string)
+
+	return sessionID
+}
+
 func (app *application) BasicAuthMiddleWare() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -96,7 +129,28 @@ func (app *application) BasicAuthMiddleWare() func(http.Handler) http.Handler {
 	}
 }
 
-func (app *application) checkPostOwnership(role string, next http.HandlerFunc) http.HandlerFunc {
+// checkNotSuspended responds with a suspended error and returns false when
+// the user's account is suspended or banned, recording audit first if set.
+func (app *application) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID int64, audit *store.AuditEvent) bool {
+	suspension, err := app.store.Suspensions.GetActive(r.Context(), userID)
+	switch {
+	case err == nil:
+		if audit != nil {
+			app.audit(r, audit)
+		}
+		app.suspendedError(w, r, suspension)
+		return false
+	case !errors.Is(err, store.ErrNotFound):
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// checkPostOwnership lets authors through, along with the users whose role
+// was granted permission.
+func (app *application) checkPostOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := getUserFromContext(r)
 		post := getPostFromCtx(r)
@@ -107,15 +161,14 @@ func (app *application) checkPostOwnership(role string, next http.HandlerFunc) h
 			return
 		}
 
-		// role precedence check
-		allowed, err := app.checkRolePrecedence(r.Context(), user, role)
+		allowed, err := app.hasPermission(r.Context(), user, permission)
 		if err != nil {
 			app.internalServerError(w, r, err)
 			return
 		}
 
 		if !allowed {
-			app.forbiddenError(w, r, err)
+			app.forbiddenError(w, r, fmt.Errorf("user %d doesn't own post %d", user.ID, post.ID))
 			return
 		}
 
@@ -123,14 +176,50 @@ func (app *application) checkPostOwnership(role string, next http.HandlerFunc) h
 	})
 }
 
-func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := app.store.Roles.GetByName(ctx, roleName)
+func (app *application) checkCommentOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		comment := getCommentFromCtx(r)
 
-	if err != nil {
-		return false, err
-	}
+		if comment.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := app.hasPermission(r.Context(), user, permission)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenError(w, r, fmt.Errorf("user %d doesn't own comment %d", user.ID, comment.ID))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePermission only lets through users whose role was granted
+// permission.
+func (app *application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, err := app.hasPermission(r.Context(), getUserFromContext(r), permission)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenError(w, r, fmt.Errorf("permission %q required", permission))
+				return
+			}
 
-	return user.Role.Level >= role.Level, nil
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
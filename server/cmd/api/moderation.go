@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/mailer"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type AssignReportPayload struct {
+	// AssigneeID defaults to the current user
+	AssigneeID *int64 `json:"assignee_id" validate:"omitempty,gte=1"`
+}
+
+type ResolveReportPayload struct {
+	Action string `json:"action" validate:"required,oneof=dismiss hide warn suspend"`
+	Note   string `json:"note" validate:"required_if=Action suspend,max=1000"`
+	// SuspendDays is how long a suspend action lasts, indefinitely when unset
+	SuspendDays *int `json:"suspend_days" validate:"omitempty,gte=1,lte=365"`
+}
+
+// listReportsHandler godoc
+//
+//	@Summary		Lists reports
+//	@Description	Lists the moderation queue, oldest reports first
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			status		query		string	false	"Status (open, resolved)"
+//	@Param			reason		query		string	false	"Reason"
+//	@Param			type		query		string	false	"Reported content (post, comment)"
+//	@Param			assignee	query		string	false	"Assignee ID, me or unassigned"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports [get]
+func (app *application) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.ReportsQuery{
+		Status: store.ReportOpen,
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if q.Assignee == "me" {
+		q.Assignee = strconv.FormatInt(getUserFromContext(r).ID, 10)
+	}
+
+	reports, err := app.store.Reports.List(r.Context(), q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reports); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getReportHandler godoc
+//
+//	@Summary		Fetches a report
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			reportID	path		int	true	"Report ID"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID} [get]
+func (app *application) getReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	report, err := app.store.Reports.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// assignReportHandler godoc
+//
+//	@Summary		Assigns a report
+//	@Description	Hands an open report over to a moderator, the current user by default
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		int					true	"Report ID"
+//	@Param			payload		body		AssignReportPayload	false	"Assignee"
+//	@Success		204			{string}	string				"Report assigned"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/assign [put]
+func (app *application) assignReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload AssignReportPayload
+
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+	assigneeID := user.ID
+
+	if payload.AssigneeID != nil && *payload.AssigneeID != user.ID {
+		assignee, err := app.store.Users.GetByID(ctx, *payload.AssigneeID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestError(w, r, fmt.Errorf("user %d doesn't exist", *payload.AssigneeID))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		allowed, err := app.hasPermission(ctx, assignee, store.PermReportsReview)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.badRequestError(w, r, fmt.Errorf("user %d can't review reports", assignee.ID))
+			return
+		}
+
+		assigneeID = assignee.ID
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditReportAssigned,
+		TargetType: store.AuditTargetReport,
+		TargetID:   &id,
+		Metadata:   map[string]any{"assignee_id": assigneeID},
+	})
+
+	if err := app.store.Reports.Assign(ctx, id, assigneeID, user.ID, audit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveReportHandler godoc
+//
+//	@Summary		Resolves a report
+//	@Description	Dismisses a report, hides the reported content, warns its author by email or suspends them. Every open report on the same content is resolved along with it.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		int						true	"Report ID"
+//	@Param			payload		body		ResolveReportPayload	true	"Resolution"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportID}/resolve [post]
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if payload.SuspendDays != nil && payload.Action != store.ActionSuspend {
+		app.badRequestError(w, r, fmt.Errorf("suspend_days only applies to the suspend action"))
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	res := store.ReportResolution{
+		Action:      payload.Action,
+		Note:        payload.Note,
+		ModeratorID: user.ID,
+		Audit: func(report *store.Report) *store.AuditEvent {
+			return auditEvent(r, &store.AuditEvent{
+				Action:     store.AuditReportResolved,
+				TargetType: store.AuditTargetReport,
+				TargetID:   &report.ID,
+				Metadata:   map[string]any{"action": payload.Action, "author_id": report.AuthorID},
+			})
+		},
+	}
+
+	if payload.Action == store.ActionWarn {
+		res.Warning = warningMail(payload.Note)
+	}
+
+	if payload.Action == store.ActionSuspend {
+		if !app.checkCanSuspend(w, r, id) {
+			return
+		}
+
+		if payload.SuspendDays != nil {
+			until := time.Now().AddDate(0, 0, *payload.SuspendDays)
+			res.SuspendUntil = &until
+		}
+	}
+
+	report, err := app.store.Reports.Resolve(ctx, id, res)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, fmt.Errorf("report %d is already resolved", id))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// warningMail composes the email warning the author of reported content,
+// along with the moderator's note.
+func warningMail(note string) func(*store.User, *store.Report) (*store.OutboxMessage, error) {
+	return func(author *store.User, report *store.Report) (*store.OutboxMessage, error) {
+		vars := struct {
+			Username    string
+			ContentType string
+			Title       string
+			Note        string
+		}{Username: author.Username, ContentType: "post", Title: report.Title, Note: note}
+
+		if report.CommentID != nil {
+			vars.ContentType = "comment"
+		}
+
+		return store.NewOutboxMessage(mailer.ContentWarningTemplate, author.Language, author.Username, author.Email, vars)
+	}
+}
+
+// checkCanSuspend responds with an error and returns false unless the author
+// of the reported content has a lower role than the current user.
+func (app *application) checkCanSuspend(w http.ResponseWriter, r *http.Request, reportID int64) bool {
+	ctx := r.Context()
+
+	report, err := app.store.Reports.GetByID(ctx, reportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return false
+	}
+
+	author, err := app.store.Users.GetByID(ctx, report.AuthorID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		// there's nobody left to suspend
+		app.badRequestError(w, r, fmt.Errorf("user %d isn't active", report.AuthorID))
+		return false
+	case err != nil:
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	if author.Role.Level >= getUserFromContext(r).Role.Level {
+		app.forbiddenError(w, r, fmt.Errorf("user %d can't be suspended by a peer", author.ID))
+		return false
+	}
+
+	return true
+}
+
+// listModerationLogHandler godoc
+//
+//	@Summary		Lists the moderation log
+//	@Description	Lists moderation actions, most recent first
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			subject_id	query		int	false	"Only the actions about this user"
+//	@Param			limit		query		int	false	"Limit"
+//	@Param			offset		query		int	false	"Offset"
+//	@Success		200			{object}	[]store.ModerationLogEntry
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/log [get]
+func (app *application) listModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.ModerationLogQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	entries, err := app.store.Reports.GetLog(r.Context(), q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
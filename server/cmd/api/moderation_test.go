@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/mailer"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testReportStore holds open reports on post 1 (1) and comment 1 (2), both
+// by the author, a resolved report (3) and an open report on a post by the
+// moderator (4). It keeps the last audit event.
+type testReportStore struct {
+	*store.MockReportStore
+	reports      map[int64]*store.Report
+	reported     map[int64]bool
+	assignee     int64
+	warning      *store.OutboxMessage
+	suspendUntil *time.Time
+	audit        *store.AuditEvent
+}
+
+func newTestReportStore() *testReportStore {
+	comment := int64(1)
+	resolved := store.ActionDismiss
+
+	return &testReportStore{
+		reports: map[int64]*store.Report{
+			1: {ID: 1, PostID: 1, AuthorID: authorID, Title: "title", Status: store.ReportOpen},
+			2: {ID: 2, PostID: 1, CommentID: &comment, AuthorID: authorID, Status: store.ReportOpen},
+			3: {ID: 3, PostID: 1, AuthorID: authorID, Status: store.ReportResolved, Resolution: &resolved},
+			4: {ID: 4, PostID: 2, AuthorID: moderatorID, Status: store.ReportOpen},
+		},
+		reported: map[int64]bool{},
+	}
+}
+
+func (s *testReportStore) Create(ctx context.Context, report *store.Report) error {
+	if s.reported[report.ReporterID] {
+		return store.ErrConflict
+	}
+
+	s.reported[report.ReporterID] = true
+	report.ID = 5
+	report.Status = store.ReportOpen
+	return nil
+}
+
+func (s *testReportStore) GetByID(ctx context.Context, reportID int64) (*store.Report, error) {
+	report, ok := s.reports[reportID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return report, nil
+}
+
+func (s *testReportStore) Assign(ctx context.Context, reportID, assigneeID, moderatorID int64, audit *store.AuditEvent) error {
+	if _, ok := s.reports[reportID]; !ok {
+		return store.ErrNotFound
+	}
+
+	s.assignee = assigneeID
+	s.audit = audit
+	return nil
+}
+
+func (s *testReportStore) Resolve(ctx context.Context, reportID int64, res store.ReportResolution) (*store.Report, error) {
+	report, ok := s.reports[reportID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	if report.Status != store.ReportOpen {
+		return nil, store.ErrConflict
+	}
+
+	if res.Action == store.ActionWarn {
+		author := &store.User{ID: report.AuthorID, Username: "author", Email: "author@example.com", Language: "fr"}
+
+		msg, err := res.Warning(author, report)
+		if err != nil {
+			return nil, err
+		}
+
+		s.warning = msg
+	}
+
+	if res.Action == store.ActionSuspend {
+		s.suspendUntil = res.SuspendUntil
+	}
+
+	s.audit = res.Audit(report)
+
+	report.Status = store.ReportResolved
+	report.Resolution = &res.Action
+	report.ResolvedBy = &res.ModeratorID
+	return report, nil
+}
+
+func TestReports(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testUserStore{}
+	app.store.Posts = &testPostStore{}
+	app.store.Comments = &testCommentStore{}
+	reports := newTestReportStore()
+	app.store.Reports = reports
+	mux := app.mount()
+
+	request := func(userID int64, method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, userID))
+
+		return req
+	}
+
+	t.Run("should report posts and comments", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPost, "/v1/posts/1/reports", `{"reason": "spam"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		rr = executeRequest(request(readerID, http.MethodPost, "/v1/posts/1/reports", `{"reason": "spam"}`), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+
+		rr = executeRequest(request(moderatorID, http.MethodPost, "/v1/posts/1/comments/1/reports", `{"reason": "hate"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"comment_id":1`) {
+			t.Errorf("expected a report on comment 1, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should reject invalid reports", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID int64
+			body   string
+		}{
+			{"own content", authorID, `{"reason": "spam"}`},
+			{"unknown reason", adminID, `{"reason": "boring"}`},
+			{"missing reason", adminID, `{}`},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(tt.userID, http.MethodPost, "/v1/posts/1/reports", tt.body), mux)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should assign reports to moderators", func(t *testing.T) {
+		rr := executeRequest(request(moderatorID, http.MethodPut, "/v1/moderation/reports/1/assign", `{}`), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if reports.assignee != moderatorID {
+			t.Errorf("expected report 1 to be assigned to user %d, got %d", moderatorID, reports.assignee)
+		}
+
+		if audit := reports.audit; audit == nil || audit.Action != store.AuditReportAssigned || *audit.ActorID != moderatorID {
+			t.Errorf("expected the assignment to be audited on behalf of user %d, got %+v", moderatorID, audit)
+		}
+
+		rr = executeRequest(request(moderatorID, http.MethodPut, "/v1/moderation/reports/1/assign", `{"assignee_id": 3}`), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if reports.assignee != adminID {
+			t.Errorf("expected report 1 to be assigned to user %d, got %d", adminID, reports.assignee)
+		}
+
+		rr = executeRequest(request(moderatorID, http.MethodPut, "/v1/moderation/reports/1/assign", `{"assignee_id": 4}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+
+		rr = executeRequest(request(moderatorID, http.MethodPut, "/v1/moderation/reports/9/assign", `{}`), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should email a warning to the author", func(t *testing.T) {
+		tests := []struct {
+			reportID    string
+			contentType string
+		}{
+			{"1", "post"},
+			{"2", "comment"},
+		}
+
+		for _, tt := range tests {
+			reports.warning = nil
+
+			rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/"+tt.reportID+"/resolve", `{"action": "warn", "note": "be nice"}`), mux)
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			msg := reports.warning
+			if msg == nil {
+				t.Fatalf("report %s: expected a warning email", tt.reportID)
+			}
+
+			if msg.Template != mailer.ContentWarningTemplate || msg.Email != "author@example.com" || msg.Locale != "fr" {
+				t.Errorf("report %s: expected a warning email to the author in their language, got %+v", tt.reportID, msg)
+			}
+
+			var vars map[string]string
+			if err := json.Unmarshal(msg.Data, &vars); err != nil {
+				t.Fatal(err)
+			}
+
+			if vars["ContentType"] != tt.contentType || vars["Note"] != "be nice" {
+				t.Errorf("report %s: expected a warning about a %s with the note, got %v", tt.reportID, tt.contentType, vars)
+			}
+
+			if audit := reports.audit; audit.Action != store.AuditReportResolved || audit.Metadata["author_id"] != authorID {
+				t.Errorf("report %s: expected the resolution to be audited along with the author, got %+v", tt.reportID, audit)
+			}
+		}
+	})
+
+	t.Run("should suspend the author", func(t *testing.T) {
+		body := `{"action": "suspend", "note": "spam", "suspend_days": 3}`
+
+		rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/4/resolve", body), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(request(adminID, http.MethodPost, "/v1/moderation/reports/4/resolve", body), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		until := reports.suspendUntil
+		if until == nil || until.Before(time.Now().AddDate(0, 0, 2)) || until.After(time.Now().AddDate(0, 0, 3)) {
+			t.Errorf("expected a suspension lasting 3 days, got %v", until)
+		}
+	})
+
+	t.Run("should not resolve a report twice", func(t *testing.T) {
+		rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/3/resolve", `{"action": "dismiss"}`), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+
+		rr = executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/9/resolve", `{"action": "dismiss"}`), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject invalid resolutions", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"unknown action", `{"action": "ban"}`},
+			{"missing action", `{}`},
+			{"suspension length without a suspension", `{"action": "hide", "suspend_days": 3}`},
+			{"suspension without a note", `{"action": "suspend"}`},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/1/resolve", tt.body), mux)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// dispatchOutbox delivers due outbox messages through the mailer. Failed
+// deliveries are retried with exponential backoff until maxAttempts is
+// reached, after which the message is dead lettered.
+func (app *application) dispatchOutbox(ctx context.Context) error {
+	cfg := app.config.outbox
+
+	messages, err := app.store.Outbox.ClaimDue(ctx, cfg.batchSize, cfg.lease)
+	if err != nil {
+		return err
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	for _, msg := range messages {
+		if msg.Data == nil {
+			app.failOutboxMessage(ctx, msg, errors.New("message data was discarded"), true)
+			continue
+		}
+
+		var data map[string]any
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			app.failOutboxMessage(ctx, msg, err, true)
+			continue
+		}
+
+		statusCode, err := app.mailer.Send(msg.Template, msg.Locale, msg.Username, msg.Email, data, !isProdEnv)
+		if err != nil {
+			app.failOutboxMessage(ctx, msg, err, msg.Attempts+1 >= cfg.maxAttempts)
+			continue
+		}
+
+		if err := app.store.Outbox.MarkSent(ctx, msg.ID); err != nil {
+			return err
+		}
+
+		app.logger.Infow("Email sent", "status code", statusCode, "outbox_id", msg.ID)
+	}
+
+	return nil
+}
+
+func (app *application) failOutboxMessage(ctx context.Context, msg store.OutboxMessage, cause error, dead bool) {
+	nextAttempt := time.Now().Add(app.outboxBackoff(msg.Attempts + 1))
+
+	if dead {
+		app.logger.Errorw("email dead lettered", "outbox_id", msg.ID, "attempts", msg.Attempts+1, "error", cause)
+	} else {
+		app.logger.Warnw("email delivery failed", "outbox_id", msg.ID, "attempts", msg.Attempts+1, "retry_at", nextAttempt, "error", cause)
+	}
+
+	if err := app.store.Outbox.MarkFailed(ctx, msg.ID, cause.Error(), nextAttempt, dead); err != nil {
+		app.logger.Errorw("error recording email failure", "outbox_id", msg.ID, "error", err)
+	}
+}
+
+func (app *application) outboxBackoff(attempts int) time.Duration {
+	cfg := app.config.outbox
+
+	backoff := time.Duration(float64(cfg.baseBackoff) * math.Pow(2, float64(attempts-1)))
+	if backoff <= 0 || backoff > cfg.maxBackoff {
+		return cfg.maxBackoff
+	}
+
+	return backoff
+}
+
+// listOutboxHandler godoc
+//
+//	@Summary		Lists outbox messages
+//	@Description	Lists queued, sent and dead lettered emails
+//	@Tags			admin
+//	@Produce		json
+//	@Param			status	query		string	false	"Status (pending, sent, dead)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.OutboxMessage
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/outbox [get]
+func (app *application) listOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.OutboxQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	messages, err := app.store.Outbox.List(r.Context(), q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// retryOutboxHandler godoc
+//
+//	@Summary		Retries a dead lettered email
+//	@Description	Puts a dead lettered outbox message back in the delivery queue
+//	@Tags			admin
+//	@Produce		json
+//	@Param			messageID	path		int		true	"Outbox message ID"
+//	@Success		202			{string}	string	"Message requeued"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/outbox/{messageID}/retry [post]
+func (app *application) retryOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "messageID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Outbox.Retry(r.Context(), id); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		case store.ErrConflict:
+			app.conflictError(w, r, errors.New("the message data was discarded, a new email has to be requested"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, "message requeued"); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type testMailer struct {
+	sent []string
+}
+
+func (m *testMailer) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	if email == "fail@example.com" {
+		return 0, errors.New("mailbox unavailable")
+	}
+
+	m.sent = append(m.sent, email)
+
+	return http.StatusOK, nil
+}
+
+type failedDelivery struct {
+	nextAttempt time.Time
+	dead        bool
+}
+
+type testOutboxStore struct {
+	*store.MockOutboxStore
+	due    []store.OutboxMessage
+	sent   []int64
+	failed map[int64]failedDelivery
+}
+
+func (s *testOutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]store.OutboxMessage, error) {
+	return s.due, nil
+}
+
+func (s *testOutboxStore) MarkSent(ctx context.Context, id int64) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+
+func (s *testOutboxStore) MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error {
+	s.failed[id] = failedDelivery{nextAttempt: nextAttempt, dead: dead}
+	return nil
+}
+
+func (s *testOutboxStore) List(ctx context.Context, q store.OutboxQuery) ([]store.OutboxMessage, error) {
+	return s.due, nil
+}
+
+func (s *testOutboxStore) Retry(ctx context.Context, id int64) error {
+	switch id {
+	case 1:
+		return nil
+	case 2:
+		return store.ErrConflict
+	default:
+		return store.ErrNotFound
+	}
+}
+
+func TestDispatchOutbox(t *testing.T) {
+	cfg := config{
+		outbox: outboxConfig{
+			batchSize:   10,
+			maxAttempts: 3,
+			baseBackoff: time.Minute,
+			maxBackoff:  time.Hour,
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	mail := &testMailer{}
+	app.mailer = mail
+
+	data := json.RawMessage(`{"Username": "author"}`)
+	outbox := &testOutboxStore{
+		due: []store.OutboxMessage{
+			{ID: 1, Email: "author@example.com", Data: data},
+			{ID: 2, Email: "fail@example.com", Data: data, Attempts: 1},
+			{ID: 3, Email: "fail@example.com", Data: data, Attempts: 2},
+			{ID: 4, Email: "author@example.com"},
+		},
+		failed: make(map[int64]failedDelivery),
+	}
+	app.store.Outbox = outbox
+
+	if err := app.dispatchOutbox(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should mark delivered messages as sent", func(t *testing.T) {
+		if len(outbox.sent) != 1 || outbox.sent[0] != 1 {
+			t.Errorf("expected message 1 to be sent, got %v", outbox.sent)
+		}
+	})
+
+	t.Run("should retry failed deliveries with backoff", func(t *testing.T) {
+		failure, ok := outbox.failed[2]
+		if !ok || failure.dead {
+			t.Fatalf("expected message 2 to be retried, got %+v", failure)
+		}
+
+		// the second attempt waits twice the base backoff
+		if d := time.Until(failure.nextAttempt); d < time.Minute || d > 2*time.Minute {
+			t.Errorf("expected the next attempt in 2m, got %v", d)
+		}
+	})
+
+	t.Run("should dead letter messages out of attempts", func(t *testing.T) {
+		if failure := outbox.failed[3]; !failure.dead {
+			t.Error("expected message 3 to be dead lettered")
+		}
+	})
+
+	t.Run("should dead letter messages without data", func(t *testing.T) {
+		if failure := outbox.failed[4]; !failure.dead {
+			t.Error("expected message 4 to be dead lettered")
+		}
+	})
+}
+
+func TestOutboxHandlers(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testUserStore{}
+	app.store.Outbox = &testOutboxStore{
+		due: []store.OutboxMessage{
+			{ID: 1, Email: "author@example.com", Data: json.RawMessage(`{"ResetURL": "secret"}`)},
+		},
+	}
+	mux := app.mount()
+
+	token := generateTestToken(t, app, adminID)
+
+	t.Run("should not list the message data", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/admin/outbox", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if strings.Contains(rr.Body.String(), "secret") {
+			t.Errorf("expected the message data to be left out, got %s", rr.Body.String())
+		}
+	})
+
+	tests := []struct {
+		name      string
+		messageID string
+		expected  int
+	}{
+		{"should requeue dead lettered messages", "1", http.StatusAccepted},
+		{"should not requeue messages without data", "2", http.StatusConflict},
+		{"should not requeue unknown messages", "3", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/admin/outbox/"+tt.messageID+"/retry", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+}
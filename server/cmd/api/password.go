@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iykeevans/go-social/server/internal/mailer"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// forgotPasswordHandler godoc
+//
+//	@Summary		Requests a password reset
+//	@Description	Emails a single-use password reset link if an active account exists for the address
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ForgotPasswordPayload	true	"Account email"
+//	@Success		202		{string}	string					"Reset requested"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/password/forgot [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// the response is the same whether or not the account exists so the
+	// endpoint can't be used to discover registered emails
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.audit(r, &store.AuditEvent{
+				Action:   store.AuditPasswordResetRequested,
+				Metadata: map[string]any{"email": payload.Email, "reason": "unknown email"},
+			})
+			app.passwordResetRequestedResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password/%s", app.config.frontendURL, plainToken),
+		ExpiresIn: app.config.mail.passwordResetExp.String(),
+	}
+
+	mail, err := store.NewOutboxMessage(mailer.PasswordResetTemplate, user.Language, user.Username, user.Email, vars)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.CreatePasswordReset(ctx, user.ID, hashToken(plainToken), app.config.mail.passwordResetExp, mail); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.audit(r, &store.AuditEvent{
+		Action:     store.AuditPasswordResetRequested,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+	})
+
+	app.passwordResetRequestedResponse(w, r)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// resetPasswordHandler godoc
+//
+//	@Summary		Resets a password
+//	@Description	Sets a new password using a reset token and signs the user out everywhere
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		204		{string}	string					"Password reset"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/password/reset [put]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := &store.User{}
+
+	// hash user password
+	if err := user.Password.Set(payload.Password); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.ResetPassword(r.Context(), payload.Token, user); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, &store.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     store.AuditPasswordReset,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) passwordResetRequestedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "if an account exists for this email, a password reset link has been sent"
+
+	if err := app.jsonResponse(w, http.StatusAccepted, message); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type testPasswordUserStore struct {
+	*store.MockUserStore
+	resets   map[int64]string
+	password string
+}
+
+func (s *testPasswordUserStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	if email != "author@example.com" {
+		return nil, store.ErrNotFound
+	}
+
+	return &store.User{ID: authorID, Username: "author", Email: email}, nil
+}
+
+func (s *testPasswordUserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration, mail *store.OutboxMessage) error {
+	s.resets[userID] = token
+	return nil
+}
+
+func (s *testPasswordUserStore) ResetPassword(ctx context.Context, token string, user *store.User) error {
+	if token != "valid" {
+		return store.ErrNotFound
+	}
+
+	user.ID = authorID
+
+	if err := user.Password.Compare("new-password"); err != nil {
+		return err
+	}
+
+	s.password = "new-password"
+
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	app := newTestApplication(t, config{})
+	users := &testPasswordUserStore{resets: make(map[int64]string)}
+	app.store.Users = users
+	mux := app.mount()
+
+	t.Run("should create a reset for a registered email", func(t *testing.T) {
+		body := `{"email": "author@example.com"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+		token, ok := users.resets[authorID]
+		if !ok {
+			t.Fatal("expected a password reset to be created")
+		}
+
+		// only the hash of the token is stored
+		if len(token) != 64 {
+			t.Errorf("expected a hashed token, got %q", token)
+		}
+	})
+
+	t.Run("should respond the same for an unknown email", func(t *testing.T) {
+		body := `{"email": "nobody@example.com"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+		if len(users.resets) != 1 {
+			t.Errorf("expected no password reset for an unknown email, got %d", len(users.resets))
+		}
+	})
+
+	t.Run("should reset the password with a valid token", func(t *testing.T) {
+		body := `{"token": "valid", "password": "new-password"}`
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/authentication/password/reset", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		if users.password != "new-password" {
+			t.Error("expected the password to be reset")
+		}
+	})
+
+	t.Run("should not reset the password with an unknown token", func(t *testing.T) {
+		body := `{"token": "unknown", "password": "new-password"}`
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/authentication/password/reset", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should validate the new password", func(t *testing.T) {
+		body := `{"token": "valid", "password": "no"}`
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/authentication/password/reset", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
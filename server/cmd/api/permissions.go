@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// permissionCache keeps the role to permission table in memory so that
+// authorizing a request doesn't hit the database. It's reloaded once ttl has
+// passed, or right away after invalidate.
+type permissionCache struct {
+	load func(context.Context) (map[int64][]string, error)
+	ttl  time.Duration
+
+	mu        sync.RWMutex
+	roles     map[int64]map[string]bool
+	expiresAt time.Time
+}
+
+func newPermissionCache(load func(context.Context) (map[int64][]string, error), ttl time.Duration) *permissionCache {
+	return &permissionCache{load: load, ttl: ttl}
+}
+
+// has reports whether roleID was granted permission.
+func (c *permissionCache) has(ctx context.Context, roleID int64, permission string) (bool, error) {
+	c.mu.RLock()
+	if time.Now().Before(c.expiresAt) {
+		granted := c.roles[roleID][permission]
+		c.mu.RUnlock()
+		return granted, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// another request may have reloaded it in the meantime
+	if !time.Now().Before(c.expiresAt) {
+		permissions, err := c.load(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		roles := make(map[int64]map[string]bool, len(permissions))
+		for roleID, names := range permissions {
+			roles[roleID] = make(map[string]bool, len(names))
+			for _, name := range names {
+				roles[roleID][name] = true
+			}
+		}
+
+		c.roles = roles
+		c.expiresAt = time.Now().Add(c.ttl)
+	}
+
+	return c.roles[roleID][permission], nil
+}
+
+// invalidate makes the next lookup reload the table.
+func (c *permissionCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expiresAt = time.Time{}
+}
+
+func (app *application) hasPermission(ctx context.Context, user *store.User, permission string) (bool, error) {
+	return app.permissions.has(ctx, user.Role.ID, permission)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testUserStore{}
+	app.store.Posts = &testPostStore{}
+	app.store.Comments = &testCommentStore{}
+	mux := app.mount()
+
+	tokens := make(map[int64]string)
+	for _, userID := range []int64{authorID, moderatorID, adminID, readerID} {
+		tokens[userID] = generateTestToken(t, app, userID)
+	}
+
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		status  int
+		allowed []int64
+	}{
+		{http.MethodPatch, "/v1/posts/1", `{}`, http.StatusOK, []int64{authorID, moderatorID, adminID}},
+		{http.MethodDelete, "/v1/posts/1", ``, http.StatusOK, []int64{authorID, adminID}},
+		{http.MethodPut, "/v1/posts/1/restore", ``, http.StatusOK, []int64{authorID, adminID}},
+		{http.MethodPut, "/v1/posts/2/restore", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodPatch, "/v1/posts/1/comments/1", `{"content": "edited"}`, http.StatusOK, []int64{authorID, moderatorID, adminID}},
+		{http.MethodDelete, "/v1/posts/1/comments/1", ``, http.StatusNoContent, []int64{authorID, moderatorID, adminID}},
+
+		{http.MethodGet, "/v1/moderation/reports", ``, http.StatusOK, []int64{moderatorID, adminID}},
+		{http.MethodGet, "/v1/moderation/reports/1", ``, http.StatusOK, []int64{moderatorID, adminID}},
+		{http.MethodPut, "/v1/moderation/reports/1/assign", `{}`, http.StatusNoContent, []int64{moderatorID, adminID}},
+		{http.MethodPost, "/v1/moderation/reports/1/resolve", `{"action": "dismiss"}`, http.StatusOK, []int64{moderatorID, adminID}},
+		{http.MethodGet, "/v1/moderation/log", ``, http.StatusOK, []int64{moderatorID, adminID}},
+
+		{http.MethodGet, "/v1/admin/outbox", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodPost, "/v1/admin/outbox/1/retry", ``, http.StatusAccepted, []int64{adminID}},
+		{http.MethodGet, "/v1/admin/roles", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodPost, "/v1/admin/roles", `{"name": "editor", "level": 1}`, http.StatusCreated, []int64{adminID}},
+		{http.MethodPut, "/v1/admin/users/4/role", `{"role": "user"}`, http.StatusOK, []int64{adminID}},
+		{http.MethodGet, "/v1/admin/users/4/role-changes", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodGet, "/v1/admin/users/4/suspensions", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodPost, "/v1/admin/users/4/suspensions", `{"kind": "ban", "reason": "spam"}`, http.StatusCreated, []int64{adminID}},
+		{http.MethodDelete, "/v1/admin/users/4/suspensions", ``, http.StatusNoContent, []int64{adminID}},
+		{http.MethodGet, "/v1/admin/audit-events", ``, http.StatusOK, []int64{adminID}},
+		{http.MethodGet, "/v1/admin/audit-events/export", ``, http.StatusOK, []int64{adminID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			for _, userID := range []int64{authorID, moderatorID, adminID, readerID} {
+				req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("Authorization", "Bearer "+tokens[userID])
+
+				rr := executeRequest(req, mux)
+
+				allowed := false
+				for _, id := range tt.allowed {
+					allowed = allowed || id == userID
+				}
+
+				switch {
+				case allowed && rr.Code != tt.status:
+					t.Errorf("user %d should get %d, got %d", userID, tt.status, rr.Code)
+				case !allowed && rr.Code != http.StatusForbidden:
+					t.Errorf("user %d should be forbidden, got %d", userID, rr.Code)
+				}
+			}
+		})
+	}
+}
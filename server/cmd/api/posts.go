@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/iykeevans/go-social/server/internal/store"
@@ -17,7 +22,7 @@ const postCtx postKey = "post"
 type CreatePostPayload struct {
 	Title   string   `json:"title" validate:"required,max=100"`
 	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Tags    []string `json:"tags"` // capped at store.MaxTagsPerPost by NormalizeTags
 }
 
 // CreatePost godoc
@@ -47,12 +52,18 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	tags, err := store.NormalizeTags(payload.Tags)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
 	user := getUserFromContext(r)
 
 	post := &store.Post{
 		Title:   payload.Title,
 		Content: payload.Content,
-		Tags:    payload.Tags,
+		Tags:    tags,
 		UserID:  user.ID,
 	}
 
@@ -72,20 +83,23 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // GetPost godoc
 //
 //	@Summary		Fetches a post
-//	@Description	Fetches a post by ID
+//	@Description	Fetches a post by ID with its comments and reactions. Sending its ETag in If-None-Match gets a 304 while none of them changed.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	store.Post
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id				path		int		true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached copy"
+//	@Success		200				{object}	store.Post
+//	@Success		304				{string}	string	"Not modified"
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, user.ID)
 
 	if err != nil {
 		app.internalServerError(w, r, err)
@@ -94,6 +108,27 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
+	reactions, err := app.store.Reactions.GetSummary(r.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Reactions = reactions
+
+	etag, err := postETag(post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -103,7 +138,7 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 // DeletePost godoc
 //
 //	@Summary		Deletes a post
-//	@Description	Delete a post by ID
+//	@Description	Delete a post by ID. It can be restored within the restore window.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -114,18 +149,59 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "postID")
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	post := getPostFromCtx(r)
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditPostDeleted,
+		TargetType: store.AuditTargetPost,
+		TargetID:   &post.ID,
+		Metadata:   map[string]any{"author_id": post.UserID},
+	})
+
+	err := app.store.Posts.Delete(r.Context(), post.ID, getUserFromContext(r).ID, audit)
 
 	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, "successfully deleted post"); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+}
 
-	ctx := r.Context()
+// RestorePost godoc
+//
+//	@Summary		Restores a deleted post
+//	@Description	Restores a post deleted within the restore window. Its author can restore it if they deleted it themselves, admins can restore any post.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	store.Post
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/restore [put]
+func (app *application) restorePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
 
-	err = app.store.Posts.Delete(ctx, id)
+	ctx := r.Context()
+	deletedSince := time.Now().Add(-app.config.posts.restoreWindow)
 
+	post, err := app.store.Posts.GetDeleted(ctx, id, deletedSince)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -136,31 +212,73 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, "successfully deleted post"); err != nil {
-		app.internalServerError(w, r, err)
+	user := getUserFromContext(r)
+
+	// authors can only undo their own deletions, not a moderation one
+	deletedByAuthor := post.DeletedBy != nil && *post.DeletedBy == post.UserID
+
+	if post.UserID != user.ID || !deletedByAuthor {
+		allowed, err := app.hasPermission(ctx, user, store.PermPostsRestoreAny)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenError(w, r, fmt.Errorf("user %d doesn't own post %d", user.ID, post.ID))
+			return
+		}
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditPostRestored,
+		TargetType: store.AuditTargetPost,
+		TargetID:   &post.ID,
+		Metadata:   map[string]any{"author_id": post.UserID},
+	})
+
+	if err := app.store.Posts.Restore(ctx, post.ID, deletedSince, audit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
+
+	post.DeletedAt, post.DeletedBy = nil, nil
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title   *string   `json:"title" validate:"omitempty,max=100"`
+	Content *string   `json:"content" validate:"omitempty,max=1000"`
+	Tags    *[]string `json:"tags"` // capped at store.MaxTagsPerPost by NormalizeTags
+	// Version is the version the edit is based on. It can also be sent as
+	// an If-Match header with the post's ETag.
+	Version *int `json:"version" validate:"omitempty,gte=0"`
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID. The edit is rejected with a 409 when the post changed since the version sent in If-Match or the payload. The response carries no ETag, the next edit can send the version it returns.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int					true	"Post ID"
-//	@Param			payload	body		UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			If-Match	header		string				false	"ETag of the post the edit is based on"
+//	@Param			payload		body		UpdatePostPayload	true	"Post payload"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -175,6 +293,18 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 
 	if err := Validate.Struct(payload); err != nil {
 		app.badRequestError(w, r, err)
+		return
+	}
+
+	expected, err := expectedPostVersion(r, payload.Version)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if expected != nil && *expected != post.Version {
+		app.conflictError(w, r, store.ErrEditConflict)
+		return
 	}
 
 	if payload.Content != nil {
@@ -185,17 +315,89 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		post.Title = *payload.Title
 	}
 
-	if err := app.store.Posts.Update(r.Context(), post); err != nil {
-		app.internalServerError(w, r, err)
+	if payload.Tags != nil {
+		tags, err := store.NormalizeTags(*payload.Tags)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		post.Tags = tags
+	}
+
+	if err := app.store.Posts.Update(r.Context(), post, getUserFromContext(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrEditConflict):
+			app.conflictError(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	// no ETag is sent, as it would identify the post without its comments
+	// and reactions and never match the one getPostHandler sends. The next
+	// edit can be based on the version in the body.
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// expectedPostVersion reads the version an edit is based on from the If-Match
+// header or, failing that, the payload. It returns nil when the client sent
+// neither, or If-Match is "*".
+func expectedPostVersion(r *http.Request, payloadVersion *int) (*int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	if ifMatch == "" || ifMatch == "*" {
+		return payloadVersion, nil
+	}
+
+	// the hash after the version only tells representations apart
+	tag, _, _ := strings.Cut(strings.Trim(ifMatch, `"`), "-")
+
+	version, err := strconv.Atoi(tag)
+	if err != nil || !strings.HasPrefix(ifMatch, `"`) {
+		return nil, fmt.Errorf("If-Match must be a single ETag previously returned for the post")
+	}
+
+	if payloadVersion != nil && *payloadVersion != version {
+		return nil, fmt.Errorf("If-Match and version disagree")
+	}
+
+	return &version, nil
+}
+
+// postETag identifies the representation of post being sent. It starts with
+// the post's version, which edits are checked against, followed by a hash
+// of the post along with the comments and reactions loaded with it.
+func postETag(post *store.Post) (string, error) {
+	body, err := json.Marshal(post)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(body)
+
+	return fmt.Sprintf(`"%d-%x"`, post.Version, hash[:8]), nil
+}
+
+// etagMatches reports whether etag is one of the tags listed in an
+// If-None-Match header, using the weak comparison the header calls for.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "postID")
@@ -220,6 +422,19 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// posts of private accounts look like they don't exist to anyone
+		// but their approved followers
+		visible, err := app.store.Users.CanViewContent(ctx, getUserFromContext(r).ID, post.UserID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !visible {
+			app.notFoundError(w, r, store.ErrNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, postCtx, post)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testVersionedPostStore holds post 1 at version 2. Update fails with err
+// when it's set, the way a concurrent edit or delete would make it fail.
+type testVersionedPostStore struct {
+	*store.MockPostStore
+	version int
+	err     error
+}
+
+func (s *testVersionedPostStore) GetByID(ctx context.Context, postID int64) (*store.Post, error) {
+	return &store.Post{ID: postID, UserID: authorID, Title: "title", Version: s.version}, nil
+}
+
+func (s *testVersionedPostStore) Update(ctx context.Context, post *store.Post, editorID int64) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.version++
+	post.Version = s.version
+	return nil
+}
+
+// testCountedCommentStore returns as many comments as count.
+type testCountedCommentStore struct {
+	*store.MockCommentStore
+	count int
+}
+
+func (s *testCountedCommentStore) GetByPostID(ctx context.Context, postID, viewerID int64) ([]store.Comment, error) {
+	comments := []store.Comment{}
+	for i := 1; i <= s.count; i++ {
+		comments = append(comments, store.Comment{ID: int64(i), PostID: postID})
+	}
+
+	return comments, nil
+}
+
+func TestPostETags(t *testing.T) {
+	app := newTestApplication(t, config{})
+	posts := &testVersionedPostStore{version: 2}
+	app.store.Posts = posts
+	comments := &testCountedCommentStore{}
+	app.store.Comments = comments
+	mux := app.mount()
+
+	token := generateTestToken(t, app, authorID)
+
+	request := func(method string, headers map[string]string, body string) *http.Request {
+		req, err := http.NewRequest(method, "/v1/posts/1", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		return req
+	}
+
+	getETag := func() string {
+		t.Helper()
+
+		rr := executeRequest(request(http.MethodGet, nil, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		return rr.Header().Get("ETag")
+	}
+
+	t.Run("should not resend an unchanged post", func(t *testing.T) {
+		etag := getETag()
+		if !strings.HasPrefix(etag, `"2-`) {
+			t.Fatalf("expected an ETag for version 2, got %s", etag)
+		}
+
+		rr := executeRequest(request(http.MethodGet, map[string]string{"If-None-Match": etag}, ""), mux)
+		checkResponseCode(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("should resend the post once its comments change", func(t *testing.T) {
+		etag := getETag()
+
+		comments.count = 1
+		defer func() { comments.count = 0 }()
+
+		rr := executeRequest(request(http.MethodGet, map[string]string{"If-None-Match": etag}, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if rr.Header().Get("ETag") == etag {
+			t.Errorf("expected the ETag to change along with the comments, got %s", etag)
+		}
+	})
+
+	t.Run("should edit a post through its ETag", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPatch, map[string]string{"If-Match": getETag()}, `{"title": "new"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		// the edited post is sent without its comments and reactions, so it
+		// has no ETag of its own
+		if etag := rr.Header().Get("ETag"); etag != "" {
+			t.Errorf("expected no ETag, got %s", etag)
+		}
+
+		if !strings.Contains(rr.Body.String(), `"version":3`) {
+			t.Errorf("expected version 3, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("should reject edits based on another version", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			headers  map[string]string
+			body     string
+			expected int
+		}{
+			{"stale ETag", map[string]string{"If-Match": `"1-0011223344556677"`}, `{}`, http.StatusConflict},
+			{"stale version", nil, `{"version": 1}`, http.StatusConflict},
+			{"malformed ETag", map[string]string{"If-Match": "3"}, `{}`, http.StatusBadRequest},
+			{"disagreeing version", map[string]string{"If-Match": `"3-0011223344556677"`}, `{"version": 2}`, http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(http.MethodPatch, tt.headers, tt.body), mux)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expected response code %d got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should report edits that lost the race", func(t *testing.T) {
+		tests := []struct {
+			err      error
+			expected int
+		}{
+			{store.ErrEditConflict, http.StatusConflict},
+			{store.ErrNotFound, http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			posts.err = tt.err
+
+			rr := executeRequest(request(http.MethodPatch, nil, `{"title": "new"}`), mux)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%v: expected response code %d got %d", tt.err, tt.expected, rr.Code)
+			}
+		}
+
+		posts.err = nil
+	})
+}
+
+// testSoftDeletePostStore holds post 1 and post 2, both by the author. Post 2
+// was deleted before the restore window.
+type testSoftDeletePostStore struct {
+	*store.MockPostStore
+	deletedAt map[int64]time.Time
+	deletedBy map[int64]int64
+}
+
+func (s *testSoftDeletePostStore) GetByID(ctx context.Context, postID int64) (*store.Post, error) {
+	if _, deleted := s.deletedAt[postID]; deleted || postID > 2 {
+		return nil, store.ErrNotFound
+	}
+
+	return &store.Post{ID: postID, UserID: authorID}, nil
+}
+
+func (s *testSoftDeletePostStore) Delete(ctx context.Context, postID, deletedBy int64, audit *store.AuditEvent) error {
+	s.deletedAt[postID] = time.Now()
+	s.deletedBy[postID] = deletedBy
+	return nil
+}
+
+func (s *testSoftDeletePostStore) GetDeleted(ctx context.Context, postID int64, deletedSince time.Time) (*store.Post, error) {
+	deletedAt, deleted := s.deletedAt[postID]
+	if !deleted || deletedAt.Before(deletedSince) {
+		return nil, store.ErrNotFound
+	}
+
+	deletedBy := s.deletedBy[postID]
+	return &store.Post{ID: postID, UserID: authorID, DeletedBy: &deletedBy}, nil
+}
+
+func (s *testSoftDeletePostStore) Restore(ctx context.Context, postID int64, deletedSince time.Time, audit *store.AuditEvent) error {
+	delete(s.deletedAt, postID)
+	delete(s.deletedBy, postID)
+	return nil
+}
+
+func TestSoftDeletePosts(t *testing.T) {
+	cfg := config{
+		posts: postsConfig{restoreWindow: time.Hour},
+	}
+
+	app := newTestApplication(t, cfg)
+	app.store.Users = &testUserStore{}
+	posts := &testSoftDeletePostStore{
+		deletedAt: map[int64]time.Time{2: time.Now().Add(-2 * time.Hour)},
+		deletedBy: map[int64]int64{2: authorID},
+	}
+	app.store.Posts = posts
+	mux := app.mount()
+
+	request := func(userID int64, method, path string) *http.Request {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, userID))
+
+		return req
+	}
+
+	t.Run("should hide deleted posts", func(t *testing.T) {
+		rr := executeRequest(request(authorID, http.MethodDelete, "/v1/posts/1"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if posts.deletedBy[1] != authorID {
+			t.Errorf("expected post 1 to be deleted by user %d, got %v", authorID, posts.deletedBy)
+		}
+
+		rr = executeRequest(request(authorID, http.MethodGet, "/v1/posts/1"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should only let the author restore their post", func(t *testing.T) {
+		rr := executeRequest(request(readerID, http.MethodPut, "/v1/posts/1/restore"), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(request(authorID, http.MethodPut, "/v1/posts/1/restore"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), `"id":1`) || strings.Contains(rr.Body.String(), "deleted_by") {
+			t.Errorf("expected the restored post without its deletion, got %s", rr.Body.String())
+		}
+
+		rr = executeRequest(request(authorID, http.MethodGet, "/v1/posts/1"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should let admins delete and restore any post", func(t *testing.T) {
+		rr := executeRequest(request(adminID, http.MethodDelete, "/v1/posts/1"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		// the author can't undo an admin's deletion
+		rr = executeRequest(request(authorID, http.MethodPut, "/v1/posts/1/restore"), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(request(adminID, http.MethodPut, "/v1/posts/1/restore"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not restore posts outside the restore window", func(t *testing.T) {
+		tests := []struct {
+			name string
+			path string
+		}{
+			{"not deleted", "/v1/posts/1/restore"},
+			{"deleted too long ago", "/v1/posts/2/restore"},
+			{"unknown", "/v1/posts/3/restore"},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(authorID, http.MethodPut, tt.path), mux)
+
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusNotFound, rr.Code)
+			}
+		}
+	})
+}
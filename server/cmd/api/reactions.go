@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// ReactToPost godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Adds a reaction to a post. Reacting twice with the same kind has no effect.
+//	@Tags			reactions
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			kind	path		string	true	"Reaction (like, love, laugh, wow, sad)"
+//	@Success		200		{object}	store.ReactionSummary
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions/{kind} [put]
+func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	kind, err := reactionKindParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Reactions.Add(r.Context(), post.ID, user.ID, kind); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.reactionSummaryResponse(w, r, post.ID, user.ID)
+}
+
+// UnreactToPost godoc
+//
+//	@Summary		Removes a reaction from a post
+//	@Description	Removes one of the user's reactions from a post
+//	@Tags			reactions
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			kind	path		string	true	"Reaction (like, love, laugh, wow, sad)"
+//	@Success		200		{object}	store.ReactionSummary
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions/{kind} [delete]
+func (app *application) unreactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	kind, err := reactionKindParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Reactions.Remove(r.Context(), post.ID, user.ID, kind); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.reactionSummaryResponse(w, r, post.ID, user.ID)
+}
+
+// ListReactions godoc
+//
+//	@Summary		Lists who reacted to a post
+//	@Description	Lists the users who reacted to a post, most recent first, leaving out those the current user can't see
+//	@Tags			reactions
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			kind	query		string	false	"Reaction (like, love, laugh, wow, sad)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.Reaction
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions [get]
+func (app *application) listReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.ReactionsQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Var(q.Kind, "omitempty,oneof="+store.ReactionKinds); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	reactions, err := app.store.Reactions.ListUsers(r.Context(), post.ID, user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reactions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) reactionSummaryResponse(w http.ResponseWriter, r *http.Request, postID, userID int64) {
+	summary, err := app.store.Reactions.GetSummary(r.Context(), postID, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, summary); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func reactionKindParam(r *http.Request) (string, error) {
+	kind := chi.URLParam(r, "kind")
+
+	if err := Validate.Var(kind, "required,oneof="+store.ReactionKinds); err != nil {
+		return "", err
+	}
+
+	return kind, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type testReactionStore struct {
+	*store.MockReactionStore
+	added    []string
+	viewerID int64
+}
+
+func (s *testReactionStore) Add(ctx context.Context, postID, userID int64, kind string) error {
+	s.added = append(s.added, kind)
+	return nil
+}
+
+func (s *testReactionStore) ListUsers(ctx context.Context, postID, viewerID int64, q store.ReactionsQuery) ([]store.Reaction, error) {
+	s.viewerID = viewerID
+	return []store.Reaction{}, nil
+}
+
+func TestReactions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Posts = &testPostStore{}
+	reactions := &testReactionStore{}
+	app.store.Reactions = reactions
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+	}{
+		{"should react with a known kind", http.MethodPut, "/v1/posts/1/reactions/love", http.StatusOK},
+		{"should reject unknown kinds", http.MethodPut, "/v1/posts/1/reactions/meh", http.StatusBadRequest},
+		{"should remove a reaction", http.MethodDelete, "/v1/posts/1/reactions/love", http.StatusOK},
+		{"should list who reacted", http.MethodGet, "/v1/posts/1/reactions?kind=wow", http.StatusOK},
+		{"should reject listing unknown kinds", http.MethodGet, "/v1/posts/1/reactions?kind=meh", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+
+	if len(reactions.added) != 1 || reactions.added[0] != "love" {
+		t.Errorf("expected a single love reaction, got %v", reactions.added)
+	}
+
+	// who reacted is filtered by what the current user can see
+	if reactions.viewerID != readerID {
+		t.Errorf("expected reactions listed for user %d, got %d", readerID, reactions.viewerID)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type CreateReportPayload struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence sexual misinformation other"`
+	Details string `json:"details" validate:"max=1000"`
+}
+
+// reportPostHandler godoc
+//
+//	@Summary		Reports a post
+//	@Description	Flags a post for the moderators to review
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int					true	"Post ID"
+//	@Param			payload	body		CreateReportPayload	true	"Report payload"
+//	@Success		201		{object}	store.Report
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reports [post]
+func (app *application) reportPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	app.createReport(w, r, &store.Report{
+		PostID:   post.ID,
+		AuthorID: post.UserID,
+		Title:    post.Title,
+		Content:  post.Content,
+	})
+}
+
+// reportCommentHandler godoc
+//
+//	@Summary		Reports a comment
+//	@Description	Flags a comment for the moderators to review
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int					true	"Post ID"
+//	@Param			commentID	path		int					true	"Comment ID"
+//	@Param			payload		body		CreateReportPayload	true	"Report payload"
+//	@Success		201			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/reports [post]
+func (app *application) reportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	if comment.Deleted || comment.Hidden {
+		app.notFoundError(w, r, store.ErrNotFound)
+		return
+	}
+
+	app.createReport(w, r, &store.Report{
+		PostID:    comment.PostID,
+		CommentID: &comment.ID,
+		AuthorID:  comment.UserID,
+		Content:   comment.Content,
+	})
+}
+
+// createReport files report, which already describes the reported content,
+// on behalf of the current user.
+func (app *application) createReport(w http.ResponseWriter, r *http.Request, report *store.Report) {
+	var payload CreateReportPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if report.AuthorID == user.ID {
+		app.badRequestError(w, r, fmt.Errorf("users can't report their own content"))
+		return
+	}
+
+	report.ReporterID = user.ID
+	report.Reason = payload.Reason
+	report.Details = payload.Details
+
+	if err := app.store.Reports.Create(r.Context(), report); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, fmt.Errorf("content already reported"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/diff"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// RevisionDiff shows what a revision changed compared to the previous one,
+// BaseVersion. The first revision is compared to an empty post. BaseVersion
+// and the changes are nil when the previous version predates the post's
+// history, as there's nothing to compare to.
+type RevisionDiff struct {
+	Revision    store.PostRevision `json:"revision"`
+	BaseVersion *int               `json:"base_version"`
+	Title       []diff.Line        `json:"title"`
+	Content     []diff.Line        `json:"content"`
+	TagsAdded   []string           `json:"tags_added"`
+	TagsRemoved []string           `json:"tags_removed"`
+}
+
+// listPostRevisionsHandler godoc
+//
+//	@Summary		Lists a post's revisions
+//	@Description	Lists every version of a post, the most recent first
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Success		200		{object}	[]store.PostRevision
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions [get]
+func (app *application) listPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Posts.GetRevisions(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostRevisionDiffHandler godoc
+//
+//	@Summary		Fetches a post revision
+//	@Description	Fetches a version of a post along with a line diff against the version before it, if it's known
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			version	path		int	true	"Version"
+//	@Success		200		{object}	RevisionDiff
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/{version} [get]
+func (app *application) getPostRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	ctx := r.Context()
+
+	revision, err := app.store.Posts.GetRevision(ctx, post.ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	result := RevisionDiff{Revision: *revision}
+
+	// the first revision is diffed against an empty post
+	base := &store.PostRevision{}
+
+	if version > 0 {
+		base, err = app.store.Posts.GetRevision(ctx, post.ID, version-1)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			// history started after the previous version, for posts that
+			// predate it
+			if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+				app.internalServerError(w, r, err)
+			}
+			return
+		case err != nil:
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		result.BaseVersion = &base.Version
+	}
+
+	result.Title = diff.Lines(base.Title, revision.Title)
+	result.Content = diff.Lines(base.Content, revision.Content)
+	result.TagsAdded = []string{}
+	result.TagsRemoved = []string{}
+
+	for _, tag := range revision.Tags {
+		if !slices.Contains(base.Tags, tag) {
+			result.TagsAdded = append(result.TagsAdded, tag)
+		}
+	}
+
+	for _, tag := range base.Tags {
+		if !slices.Contains(revision.Tags, tag) {
+			result.TagsRemoved = append(result.TagsRemoved, tag)
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testRevisionPostStore holds versions 0 and 1 of post 1. The history of
+// post 2 started at version 3, when revisions started being recorded.
+type testRevisionPostStore struct {
+	testPostStore
+}
+
+func (s *testRevisionPostStore) GetRevision(ctx context.Context, postID int64, version int) (*store.PostRevision, error) {
+	revisions := map[int64]map[int]*store.PostRevision{
+		1: {
+			0: {PostID: 1, Version: 0, Title: "title", Content: "first\nsecond", Tags: []string{"go"}},
+			1: {PostID: 1, Version: 1, Title: "title", Content: "first\nthird", Tags: []string{"sql"}},
+		},
+		2: {
+			3: {PostID: 2, Version: 3, Title: "title", Content: "content"},
+		},
+	}
+
+	revision, ok := revisions[postID][version]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return revision, nil
+}
+
+func TestGetPostRevisionDiff(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Posts = &testRevisionPostStore{}
+	mux := app.mount()
+
+	get := func(path string) (int, RevisionDiff) {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, readerID))
+
+		rr := executeRequest(req, mux)
+
+		var body struct {
+			Data RevisionDiff `json:"data"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return rr.Code, body.Data
+	}
+
+	t.Run("should diff a revision against the previous one", func(t *testing.T) {
+		code, result := get("/v1/posts/1/revisions/1")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if result.BaseVersion == nil || *result.BaseVersion != 0 {
+			t.Fatalf("expected version 0 as the base, got %v", result.BaseVersion)
+		}
+
+		if len(result.Content) != 3 || result.Content[1].Text != "second" || result.Content[2].Text != "third" {
+			t.Errorf("expected the second line to be replaced, got %+v", result.Content)
+		}
+
+		if len(result.TagsAdded) != 1 || result.TagsAdded[0] != "sql" || len(result.TagsRemoved) != 1 || result.TagsRemoved[0] != "go" {
+			t.Errorf("expected sql to replace go, got %v and %v", result.TagsAdded, result.TagsRemoved)
+		}
+	})
+
+	t.Run("should diff the first revision against an empty post", func(t *testing.T) {
+		code, result := get("/v1/posts/1/revisions/0")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if result.BaseVersion != nil || len(result.Content) != 2 || len(result.TagsAdded) != 1 {
+			t.Errorf("expected the whole post to be added, got %+v", result)
+		}
+	})
+
+	t.Run("should not diff a revision without a known base", func(t *testing.T) {
+		code, result := get("/v1/posts/2/revisions/3")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if result.Revision.Version != 3 || result.BaseVersion != nil {
+			t.Errorf("expected version 3 without a base, got %+v", result)
+		}
+
+		if result.Title != nil || result.Content != nil || result.TagsAdded != nil || result.TagsRemoved != nil {
+			t.Errorf("expected no changes, got %+v", result)
+		}
+	})
+
+	t.Run("should not find unknown revisions", func(t *testing.T) {
+		code, _ := get("/v1/posts/1/revisions/2")
+		checkResponseCode(t, http.StatusNotFound, code)
+	})
+}
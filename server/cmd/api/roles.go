@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type CreateRolePayload struct {
+	Name        string `json:"name" validate:"required,lowercase,alphanum,max=50"`
+	Description string `json:"description" validate:"max=255"`
+	Level       int64  `json:"level" validate:"gte=0"`
+	// Permissions can only be ones the current user holds
+	Permissions []string `json:"permissions" validate:"dive,max=64"`
+}
+
+type UpdateUserRolePayload struct {
+	Role   string `json:"role" validate:"required,max=50"`
+	Reason string `json:"reason" validate:"max=1000"`
+}
+
+// listRolesHandler godoc
+//
+//	@Summary		Lists roles
+//	@Description	Lists the roles from the least to the most privileged
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	[]store.Role
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [get]
+func (app *application) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.store.Roles.GetAll(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, roles); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createRoleHandler godoc
+//
+//	@Summary		Creates a role
+//	@Description	Creates a custom role. Its level must be lower than the current user's and it can only be granted permissions the current user holds.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateRolePayload	true	"Role"
+//	@Success		201		{object}	store.Role
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [post]
+func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRolePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	ctx := r.Context()
+
+	if payload.Level >= admin.Role.Level {
+		app.forbiddenError(w, r, fmt.Errorf("role level %d isn't below %d", payload.Level, admin.Role.Level))
+		return
+	}
+
+	for _, permission := range payload.Permissions {
+		allowed, err := app.hasPermission(ctx, admin, permission)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenError(w, r, fmt.Errorf("permission %q can't be granted by user %d", permission, admin.ID))
+			return
+		}
+	}
+
+	role := &store.Role{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Level:       payload.Level,
+		Permissions: payload.Permissions,
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditRoleCreated,
+		TargetType: store.AuditTargetRole,
+		Metadata:   map[string]any{"name": role.Name, "level": role.Level, "permissions": role.Permissions},
+	})
+
+	if err := app.store.Roles.Create(ctx, role, audit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, fmt.Errorf("role %q already exists", role.Name))
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestError(w, r, fmt.Errorf("unknown permission"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.permissions.invalidate()
+
+	if err := app.jsonResponse(w, http.StatusCreated, role); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateUserRoleHandler godoc
+//
+//	@Summary		Changes a user's role
+//	@Description	Moves a user to another role. Admins can't change their own role nor the role of their peers, and can't grant a role above their own.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int						true	"User ID"
+//	@Param			payload	body		UpdateUserRolePayload	true	"Role"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/role [put]
+func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload UpdateUserRolePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	ctx := r.Context()
+
+	if userID == admin.ID {
+		app.forbiddenError(w, r, fmt.Errorf("user %d can't change their own role", admin.ID))
+		return
+	}
+
+	role, err := app.store.Roles.GetByName(ctx, payload.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestError(w, r, fmt.Errorf("role %q doesn't exist", payload.Role))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if role.Level > admin.Role.Level {
+		app.forbiddenError(w, r, fmt.Errorf("role %q is above user %d's", role.Name, admin.ID))
+		return
+	}
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.Role.Level >= admin.Role.Level {
+		app.forbiddenError(w, r, fmt.Errorf("user %d's role can't be changed by a peer", user.ID))
+		return
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditRoleChanged,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+		Metadata:   map[string]any{"old_role": user.Role.Name, "new_role": role.Name, "reason": payload.Reason},
+	})
+
+	if err := app.store.Users.UpdateRole(ctx, user.ID, role, admin.ID, payload.Reason, audit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the cached copy would otherwise keep the old role until it expires
+	if app.config.redisCfg.enabled {
+		if err := app.cacheStorage.Users.Delete(ctx, user.ID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	user.Role = *role
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// listRoleChangesHandler godoc
+//
+//	@Summary		Lists a user's role changes
+//	@Description	Lists the role changes of a user, most recent first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	[]store.RoleChange
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/role-changes [get]
+func (app *application) listRoleChangesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	changes, err := app.store.Roles.GetChanges(r.Context(), userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, changes); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+	"github.com/iykeevans/go-social/server/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+// testRoleUserStore records role changes along with their audit events.
+// User 5 is another admin and user 99 doesn't exist.
+type testRoleUserStore struct {
+	testUserStore
+	changed map[int64]string
+	audits  map[int64]*store.AuditEvent
+}
+
+func (s *testRoleUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	switch userID {
+	case 5:
+		return &store.User{ID: userID, Role: store.Role{ID: 3, Name: "admin", Level: 3}}, nil
+	case 99:
+		return nil, store.ErrNotFound
+	}
+
+	return s.testUserStore.GetByID(ctx, userID)
+}
+
+func (s *testRoleUserStore) UpdateRole(ctx context.Context, userID int64, role *store.Role, changedBy int64, reason string, audit *store.AuditEvent) error {
+	s.changed[userID] = role.Name
+	s.audits[userID] = audit
+	return nil
+}
+
+// testLevelRoleStore holds the built in roles along with an owner role above
+// admins.
+type testLevelRoleStore struct {
+	*store.MockRoleStore
+	created *store.Role
+}
+
+func (s *testLevelRoleStore) GetByName(ctx context.Context, name string) (*store.Role, error) {
+	levels := map[string]int64{"user": 1, "moderator": 2, "admin": 3, "owner": 4}
+
+	level, ok := levels[name]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return &store.Role{ID: level, Name: name, Level: level}, nil
+}
+
+func (s *testLevelRoleStore) Create(ctx context.Context, role *store.Role, audit *store.AuditEvent) error {
+	if _, err := s.GetByName(ctx, role.Name); err == nil {
+		return store.ErrConflict
+	}
+
+	role.ID = 5
+	s.created = role
+	return nil
+}
+
+func TestRoles(t *testing.T) {
+	withRedis := config{
+		redisCfg: redisConfig{
+			enabled: true,
+		},
+	}
+
+	app := newTestApplication(t, withRedis)
+	users := &testRoleUserStore{changed: map[int64]string{}, audits: map[int64]*store.AuditEvent{}}
+	app.store.Users = users
+	roles := &testLevelRoleStore{}
+	app.store.Roles = roles
+	mux := app.mount()
+
+	mockCacheStore := app.cacheStorage.Users.(*cache.MockUserStore)
+	mockCacheStore.On("Get", mock.Anything).Return(nil, nil)
+	mockCacheStore.On("Set", mock.Anything).Return(nil)
+
+	request := func(method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, adminID))
+
+		return req
+	}
+
+	t.Run("should change a user's role and drop their cached copy", func(t *testing.T) {
+		mockCacheStore.On("Delete", readerID).Return(nil).Once()
+
+		rr := executeRequest(request(http.MethodPut, "/v1/admin/users/4/role", `{"role": "moderator", "reason": "helpful"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if users.changed[readerID] != "moderator" {
+			t.Errorf("expected user %d to become a moderator, got %v", readerID, users.changed)
+		}
+
+		if audit := users.audits[readerID]; audit == nil || audit.Metadata["new_role"] != "moderator" || audit.Metadata["reason"] != "helpful" {
+			t.Errorf("expected the role change to be audited, got %+v", audit)
+		}
+
+		mockCacheStore.AssertCalled(t, "Delete", readerID)
+	})
+
+	t.Run("should fail when the cached copy can't be dropped", func(t *testing.T) {
+		mockCacheStore.On("Delete", authorID).Return(errors.New("connection refused")).Once()
+
+		rr := executeRequest(request(http.MethodPut, "/v1/admin/users/1/role", `{"role": "moderator"}`), mux)
+		checkResponseCode(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("should reject role changes admins aren't entitled to", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			path     string
+			body     string
+			expected int
+		}{
+			{"own role", "/v1/admin/users/3/role", `{"role": "user"}`, http.StatusForbidden},
+			{"peer", "/v1/admin/users/5/role", `{"role": "user"}`, http.StatusForbidden},
+			{"role above their own", "/v1/admin/users/4/role", `{"role": "owner"}`, http.StatusForbidden},
+			{"unknown role", "/v1/admin/users/4/role", `{"role": "editor"}`, http.StatusBadRequest},
+			{"missing role", "/v1/admin/users/4/role", `{}`, http.StatusBadRequest},
+			{"unknown user", "/v1/admin/users/99/role", `{"role": "user"}`, http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(http.MethodPut, tt.path, tt.body), mux)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expected response code %d got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should create roles below the admin's", func(t *testing.T) {
+		rr := executeRequest(request(http.MethodPost, "/v1/admin/roles", `{"name": "editor", "level": 2, "permissions": ["posts:update:any"]}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if roles.created == nil || roles.created.Name != "editor" || len(roles.created.Permissions) != 1 {
+			t.Errorf("expected an editor role, got %+v", roles.created)
+		}
+
+		tests := []struct {
+			name     string
+			body     string
+			expected int
+		}{
+			{"existing role", `{"name": "user", "level": 1}`, http.StatusConflict},
+			{"level of their own", `{"name": "owner", "level": 3}`, http.StatusForbidden},
+			{"invalid name", `{"name": "Chief Editor", "level": 1}`, http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(http.MethodPost, "/v1/admin/roles", tt.body), mux)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expected response code %d got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// searchHandler godoc
+//
+//	@Summary		Searches posts, users or tags
+//	@Description	Ranks posts with full-text search and matches users and tags by prefix, falling back to trigram similarity for typos. Only content visible to the current user is searched.
+//	@Tags			search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms"
+//	@Param			type	query		string	false	"What to search (posts, users, tags)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.PostSearchResult
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.SearchQuery{
+		Type:   store.SearchPosts,
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	var results any
+
+	switch q.Type {
+	case store.SearchUsers:
+		results, err = app.store.Search.SearchUsers(ctx, user.ID, q)
+	case store.SearchTags:
+		results, err = app.store.Search.SearchTags(ctx, user.ID, q)
+	default:
+		results, err = app.store.Search.SearchPosts(ctx, user.ID, q)
+	}
+
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
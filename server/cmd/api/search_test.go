@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testSearchStore returns one result of each kind and records the last query.
+type testSearchStore struct {
+	*store.MockSearchStore
+	query    store.SearchQuery
+	viewerID int64
+}
+
+func (s *testSearchStore) SearchPosts(ctx context.Context, viewerID int64, q store.SearchQuery) ([]store.PostSearchResult, error) {
+	s.query, s.viewerID = q, viewerID
+	return []store.PostSearchResult{{ID: 1, Title: "<mark>gophers</mark>"}}, nil
+}
+
+func (s *testSearchStore) SearchUsers(ctx context.Context, viewerID int64, q store.SearchQuery) ([]store.UserSearchResult, error) {
+	s.query, s.viewerID = q, viewerID
+	return []store.UserSearchResult{{ID: 2, Username: "gopher"}}, nil
+}
+
+func (s *testSearchStore) SearchTags(ctx context.Context, viewerID int64, q store.SearchQuery) ([]store.TagSearchResult, error) {
+	s.query, s.viewerID = q, viewerID
+	return []store.TagSearchResult{{Tag: "golang", Posts: 3}}, nil
+}
+
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t, config{})
+	search := &testSearchStore{}
+	app.store.Search = search
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	request := func(path string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return req
+	}
+
+	t.Run("should search posts by default", func(t *testing.T) {
+		rr := executeRequest(request("/v1/search?q=%20gophers%20"), mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.PostSearchResult `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 1 || body.Data[0].ID != 1 {
+			t.Errorf("expected post 1, got %+v", body.Data)
+		}
+
+		if search.query.Q != "gophers" || search.query.Limit != 20 || search.viewerID != readerID {
+			t.Errorf("expected a trimmed query for user %d with the default limit, got %+v", readerID, search.query)
+		}
+	})
+
+	t.Run("should search users and tags", func(t *testing.T) {
+		tests := []struct {
+			kind     string
+			expected string
+		}{
+			{store.SearchUsers, `"username":"gopher"`},
+			{store.SearchTags, `"tag":"golang"`},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request("/v1/search?q=go&type="+tt.kind+"&limit=5&offset=10"), mux)
+
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("expected %s results to contain %s, got %s", tt.kind, tt.expected, rr.Body.String())
+			}
+
+			if search.query.Type != tt.kind || search.query.Limit != 5 || search.query.Offset != 10 {
+				t.Errorf("expected a %s search paged by the query string, got %+v", tt.kind, search.query)
+			}
+		}
+	})
+
+	t.Run("should reject invalid searches", func(t *testing.T) {
+		tests := []struct {
+			name string
+			path string
+		}{
+			{"missing terms", "/v1/search"},
+			{"blank terms", "/v1/search?q=%20%20"},
+			{"unknown type", "/v1/search?q=go&type=comments"},
+			{"limit too large", "/v1/search?q=go&limit=51"},
+			{"negative offset", "/v1/search?q=go&offset=-1"},
+			{"malformed limit", "/v1/search?q=go&limit=ten"},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(tt.path), mux)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected response code %d got %d", tt.name, http.StatusBadRequest, rr.Code)
+			}
+		}
+	})
+}
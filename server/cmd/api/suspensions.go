@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+type SuspendUserPayload struct {
+	Kind   string `json:"kind" validate:"required,oneof=suspension ban"`
+	Reason string `json:"reason" validate:"required,max=1000"`
+	// Days is how long the suspension lasts. Suspensions need one, bans last
+	// until lifted without it.
+	Days *int `json:"days" validate:"required_if=Kind suspension,omitempty,gte=1,lte=3650"`
+}
+
+// suspendUserHandler godoc
+//
+//	@Summary		Suspends or bans a user
+//	@Description	Signs the user out, keeps them from signing back in and hides their content until the suspension expires or is lifted
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int					true	"User ID"
+//	@Param			payload	body		SuspendUserPayload	true	"Suspension"
+//	@Success		201		{object}	store.Suspension
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/suspensions [post]
+func (app *application) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload SuspendUserPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.Role.Level >= admin.Role.Level {
+		app.forbiddenError(w, r, fmt.Errorf("user %d can't be suspended by a peer", user.ID))
+		return
+	}
+
+	suspension := &store.Suspension{
+		UserID:      user.ID,
+		Kind:        payload.Kind,
+		Reason:      payload.Reason,
+		SuspendedBy: &admin.ID,
+	}
+
+	if payload.Days != nil {
+		expiresAt := time.Now().AddDate(0, 0, *payload.Days)
+		suspension.ExpiresAt = &expiresAt
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditUserSuspended,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &user.ID,
+		Metadata:   map[string]any{"kind": suspension.Kind, "expires_at": suspension.ExpiresAt},
+	})
+
+	if err := app.store.Suspensions.Create(ctx, suspension, audit); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, suspension); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// liftSuspensionHandler godoc
+//
+//	@Summary		Lifts a user's suspension
+//	@Description	Lifts the suspensions and bans currently in effect for a user
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Suspension lifted"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/suspensions [delete]
+func (app *application) liftSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	audit := auditEvent(r, &store.AuditEvent{
+		Action:     store.AuditSuspensionLifted,
+		TargetType: store.AuditTargetUser,
+		TargetID:   &userID,
+	})
+
+	if err := app.store.Suspensions.Lift(r.Context(), userID, getUserFromContext(r).ID, audit); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listSuspensionsHandler godoc
+//
+//	@Summary		Lists a user's suspensions
+//	@Description	Lists every suspension and ban of a user, lifted ones included, most recent first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	[]store.Suspension
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/suspensions [get]
+func (app *application) listSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	suspensions, err := app.store.Suspensions.GetByUserID(r.Context(), userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, suspensions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
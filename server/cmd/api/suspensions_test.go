@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// testSuspendableUserStore signs the reader in with "password". User 99
+// doesn't exist.
+type testSuspendableUserStore struct {
+	testUserStore
+}
+
+func (s *testSuspendableUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	if userID == 99 {
+		return nil, store.ErrNotFound
+	}
+
+	return s.testUserStore.GetByID(ctx, userID)
+}
+
+func (s *testSuspendableUserStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	user := &store.User{ID: readerID, Email: email}
+	if err := user.Password.Set("password"); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// testSuspensionStore keeps the suspensions in effect by user, along with
+// every suspension ever created.
+type testSuspensionStore struct {
+	*store.MockSuspensionStore
+	active  map[int64]*store.Suspension
+	history []store.Suspension
+}
+
+func (s *testSuspensionStore) Create(ctx context.Context, suspension *store.Suspension, audit *store.AuditEvent) error {
+	suspension.ID = int64(len(s.history) + 1)
+	s.active[suspension.UserID] = suspension
+	s.history = append(s.history, *suspension)
+	return nil
+}
+
+func (s *testSuspensionStore) GetActive(ctx context.Context, userID int64) (*store.Suspension, error) {
+	suspension, ok := s.active[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return suspension, nil
+}
+
+func (s *testSuspensionStore) GetByUserID(ctx context.Context, userID int64) ([]store.Suspension, error) {
+	return s.history, nil
+}
+
+func (s *testSuspensionStore) Lift(ctx context.Context, userID, liftedBy int64, audit *store.AuditEvent) error {
+	if _, ok := s.active[userID]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(s.active, userID)
+	return nil
+}
+
+func TestSuspensions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testSuspendableUserStore{}
+	app.store.Posts = &testPostStore{}
+	suspensions := &testSuspensionStore{active: map[int64]*store.Suspension{}}
+	app.store.Suspensions = suspensions
+	mux := app.mount()
+
+	request := func(userID int64, method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, userID))
+
+		return req
+	}
+
+	login := func() int {
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", strings.NewReader(`{"email": "reader@example.com", "password": "password"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should lock suspended users out", func(t *testing.T) {
+		rr := executeRequest(request(adminID, http.MethodPost, "/v1/admin/users/4/suspensions", `{"kind": "suspension", "reason": "spam", "days": 7}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		suspension := suspensions.active[readerID]
+		if suspension == nil || suspension.ExpiresAt == nil || time.Until(*suspension.ExpiresAt) < 6*24*time.Hour {
+			t.Fatalf("expected the reader to be suspended for 7 days, got %+v", suspension)
+		}
+
+		rr = executeRequest(request(readerID, http.MethodGet, "/v1/users/4", ""), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		checkResponseCode(t, http.StatusForbidden, login())
+	})
+
+	t.Run("should list a user's suspensions", func(t *testing.T) {
+		rr := executeRequest(request(adminID, http.MethodGet, "/v1/admin/users/4/suspensions", ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.Suspension `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 1 || body.Data[0].Reason != "spam" || *body.Data[0].SuspendedBy != adminID {
+			t.Errorf("expected the suspension by user %d, got %+v", adminID, body.Data)
+		}
+	})
+
+	t.Run("should let users back in once lifted", func(t *testing.T) {
+		rr := executeRequest(request(adminID, http.MethodDelete, "/v1/admin/users/4/suspensions", ""), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		rr = executeRequest(request(readerID, http.MethodGet, "/v1/users/4", ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		checkResponseCode(t, http.StatusCreated, login())
+
+		rr = executeRequest(request(adminID, http.MethodDelete, "/v1/admin/users/4/suspensions", ""), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should ban users until lifted", func(t *testing.T) {
+		rr := executeRequest(request(adminID, http.MethodPost, "/v1/admin/users/1/suspensions", `{"kind": "ban", "reason": "spam"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		if ban := suspensions.active[authorID]; ban == nil || ban.Kind != store.SuspensionKindBan || ban.ExpiresAt != nil {
+			t.Errorf("expected the author to be banned until lifted, got %+v", ban)
+		}
+
+		delete(suspensions.active, authorID)
+	})
+
+	t.Run("should reject invalid suspensions", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			path     string
+			body     string
+			expected int
+		}{
+			{"suspension without an end", "/v1/admin/users/4/suspensions", `{"kind": "suspension", "reason": "spam"}`, http.StatusBadRequest},
+			{"unknown kind", "/v1/admin/users/4/suspensions", `{"kind": "mute", "reason": "spam", "days": 1}`, http.StatusBadRequest},
+			{"missing reason", "/v1/admin/users/4/suspensions", `{"kind": "ban"}`, http.StatusBadRequest},
+			{"unknown user", "/v1/admin/users/99/suspensions", `{"kind": "ban", "reason": "spam"}`, http.StatusNotFound},
+			{"peer", "/v1/admin/users/3/suspensions", `{"kind": "ban", "reason": "spam"}`, http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			rr := executeRequest(request(adminID, http.MethodPost, tt.path, tt.body), mux)
+
+			if rr.Code != tt.expected {
+				t.Errorf("%s: expected response code %d got %d", tt.name, tt.expected, rr.Code)
+			}
+		}
+
+		if len(suspensions.active) != 0 {
+			t.Errorf("expected no suspensions, got %v", suspensions.active)
+		}
+	})
+
+	t.Run("should only suspend the authors of reports below the moderator", func(t *testing.T) {
+		reports := newTestReportStore()
+		reports.reports[5] = &store.Report{ID: 5, PostID: 1, AuthorID: moderatorID, Status: store.ReportOpen}
+		app.store.Reports = reports
+
+		rr := executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/1/resolve", `{"action": "suspend", "note": "spam", "suspend_days": 3}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		rr = executeRequest(request(moderatorID, http.MethodPost, "/v1/moderation/reports/5/resolve", `{"action": "suspend", "note": "spam"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+}
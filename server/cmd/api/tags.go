@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/iykeevans/go-social/server/internal/store"
+)
+
+// getTrendingTagsHandler godoc
+//
+//	@Summary		Fetches the trending tags
+//	@Description	Fetches the tags used by the most authors lately. The list is refreshed periodically.
+//	@Tags			tags
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+	}
+
+	if err := Validate.Var(limit, "gte=1,lte=50"); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.GetTrending(r.Context(), limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTagPostsHandler godoc
+//
+//	@Summary		Fetches a tag's posts
+//	@Description	Fetches the posts with a tag, with the same filters and pagination as the feed
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			since	query		string	false	"Since"
+//	@Param			until	query		string	false	"Until"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			cursor	query		string	false	"Cursor"
+//	@Param			sort	query		string	false	"Sort"
+//	@Param			tags	query		string	false	"Other tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := store.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	fq, err := parseFeedQuery(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetTagPosts(r.Context(), tag, getUserFromContext(r).ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, r, http.StatusOK, posts, nextFeedCursor(posts, fq)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/iykeevans/go-social/server/internal/auth"
 	"github.com/iykeevans/go-social/server/internal/ratelimiter"
 	"github.com/iykeevans/go-social/server/internal/store"
@@ -28,6 +31,10 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		cfg.rateLimiter.RequestsPerTimeFrame,
 		cfg.rateLimiter.TimeFrame,
 	)
+	activationLimiter := ratelimiter.NewFixedWindowLimiter(
+		cfg.activation.resendLimiter.RequestsPerTimeFrame,
+		cfg.activation.resendLimiter.TimeFrame,
+	)
 
 	return &application{
 		logger:        logger,
@@ -36,6 +43,9 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		authenticator: testAuth,
 		config:        cfg,
 		rateLimiter:   rateLimiter,
+
+		activationLimiter: activationLimiter,
+		permissions:       newPermissionCache(mockStore.Roles.GetPermissions, cfg.permissionsTTL),
 	}
 }
 
@@ -51,3 +61,73 @@ func checkResponseCode(t *testing.T, expected, actual int) {
 		t.Errorf("expected response code %d got %d", expected, actual)
 	}
 }
+
+// generateTestToken signs an access token for userID, bound to the
+// "test-session" session.
+func generateTestToken(t *testing.T, app *application, userID int64) string {
+	t.Helper()
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": userID,
+		"sid": "test-session",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// Users of the handler tests. The author wrote post 1 and comment 1, the
+// reader is a regular user who didn't.
+const (
+	authorID    int64 = 1
+	moderatorID int64 = 2
+	adminID     int64 = 3
+	readerID    int64 = 4
+)
+
+type testUserStore struct {
+	*store.MockUserStore
+}
+
+func (s *testUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	role := store.Role{ID: 1, Name: "user", Level: 1}
+
+	switch userID {
+	case moderatorID:
+		role = store.Role{ID: 2, Name: "moderator", Level: 2}
+	case adminID:
+		role = store.Role{ID: 3, Name: "admin", Level: 3}
+	}
+
+	return &store.User{ID: userID, Role: role}, nil
+}
+
+type testPostStore struct {
+	*store.MockPostStore
+}
+
+func (s *testPostStore) GetByID(ctx context.Context, postID int64) (*store.Post, error) {
+	return &store.Post{ID: postID, UserID: authorID}, nil
+}
+
+// GetDeleted returns post 1 deleted by the author and any other post deleted
+// by an admin.
+func (s *testPostStore) GetDeleted(ctx context.Context, postID int64, deletedSince time.Time) (*store.Post, error) {
+	deletedBy := adminID
+	if postID == 1 {
+		deletedBy = authorID
+	}
+
+	return &store.Post{ID: postID, UserID: authorID, DeletedBy: &deletedBy}, nil
+}
+
+type testCommentStore struct {
+	*store.MockCommentStore
+}
+
+func (s *testCommentStore) GetByID(ctx context.Context, commentID int64) (*store.Comment, error) {
+	return &store.Comment{ID: commentID, PostID: 1, UserID: authorID}, nil
+}
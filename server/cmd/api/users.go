@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/iykeevans/go-social/server/internal/store"
 )
 
@@ -15,12 +18,12 @@ const userCtx userKey = "user"
 // GetUser godoc
 //
 //	@Summary		Fetches a user profile
-//	@Description	Fetches a user profile by ID
+//	@Description	Fetches a user profile by ID along with their post, follower and following counts
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	store.UserProfile
 //	@Failure		400	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
@@ -28,11 +31,16 @@ const userCtx userKey = "user"
 //	@Router			/users/{id} [get]
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
-	if err != nil || userID < 1 {
+	if err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
+	if userID < 1 {
+		app.badRequestError(w, r, fmt.Errorf("invalid user id %d", userID))
+		return
+	}
+
 	ctx := r.Context()
 	user, err := app.getUser(ctx, userID)
 
@@ -47,7 +55,27 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+	// counts change too often to be cached along with the user
+	stats, err := app.store.Users.GetProfileStats(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	profile := store.UserProfile{
+		User:  user,
+		Stats: *stats,
+	}
+
+	if viewer := getUserFromContext(r); viewer.ID != user.ID {
+		profile.Relationship, err = app.store.Followers.GetRelationship(ctx, viewer.ID, user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, profile); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -59,13 +87,17 @@ type FollowUser struct {
 // FollowUser godoc
 //
 //	@Summary		Follow a user
-//	@Description	Follows a user by ID
+//	@Description	Follows a user by ID. Following a private account sends a follow request instead.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			userID	path		int		true	"User ID"
+//	@Success		202		{string}	string	"Follow request sent"
 //	@Success		204		{string}	string	"User followed"
-//	@Failure		400		{object}	error	"User not found"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error	"User blocked"
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"Already following"
 //	@Security		ApiKeyAuth
 //	@Router			/users/{userID}/follow [put]
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -76,29 +108,67 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if followedID == followerUser.ID {
+		app.badRequestError(w, r, fmt.Errorf("users can't follow themselves"))
+		return
+	}
+
 	ctx := r.Context()
 
-	if err := app.store.Followers.Follow(ctx, followerUser.ID, followedID); err != nil {
+	followed, err := app.getUser(ctx, followedID)
+	if err != nil {
 		switch err {
-		case store.ErrConflict:
-			app.conflictError(w, r, err)
-
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkNotBlocked(w, r, followed.ID) {
+		return
+	}
+
+	// private accounts have to approve their followers
+	if followed.IsPrivate {
+		if err := app.store.Followers.RequestFollow(ctx, followerUser.ID, followed.ID); err != nil {
+			switch err {
+			case store.ErrConflict:
+				app.conflictError(w, r, err)
+			case store.ErrNotFound:
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
 			return
 		}
 
+		if err := app.jsonResponse(w, http.StatusAccepted, "follow request sent"); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.store.Followers.Follow(ctx, followerUser.ID, followed.ID); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictError(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // UnfollowUser gdoc
 //
 //	@Summary		Unfollow a user
-//	@Description	Unfollow a user by ID
+//	@Description	Unfollow a user by ID, or withdraw a pending follow request
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
@@ -123,9 +193,7 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
-		app.internalServerError(w, r, err)
-	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // ActivateUser godoc
@@ -159,6 +227,143 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ResendActivation godoc
+//
+//	@Summary		Resends an activation invitation
+//	@Description	Issues a new invitation token for an account that hasn't been activated yet
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ResendActivationPayload	true	"Account email"
+//	@Success		202		{string}	string					"Invitation resent"
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/users/activation/resend [post]
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	email := strings.ToLower(payload.Email)
+	if allow, retryAfter := app.activationLimiter.Allow(email); !allow {
+		app.rateLimitExceededResponse(w, r, retryAfter.String())
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	_, err := app.store.Users.ReissueInvitation(r.Context(), email, hashToken(plainToken), app.config.mail.exp, func(user *store.User) (*store.OutboxMessage, error) {
+		return app.activationMail(user, plainToken)
+	})
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			// don't reveal whether the email belongs to an inactive account
+			app.activationResentResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.activationResentResponse(w, r)
+}
+
+func (app *application) activationResentResponse(w http.ResponseWriter, r *http.Request) {
+	message := "if an inactive account exists for this email, a new activation link has been sent"
+
+	if err := app.jsonResponse(w, http.StatusAccepted, message); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateAccountPayload struct {
+	IsPrivate *bool `json:"is_private"`
+}
+
+// UpdateAccount godoc
+//
+//	@Summary		Updates the current user's account
+//	@Description	Makes the account private or public. Going public approves the pending follow requests.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateAccountPayload	true	"Account settings"
+//	@Success		200		{object}	store.User
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [patch]
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateAccountPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if payload.IsPrivate != nil && *payload.IsPrivate != user.IsPrivate {
+		if err := app.store.Users.SetPrivacy(ctx, user.ID, *payload.IsPrivate); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		user.IsPrivate = *payload.IsPrivate
+
+		// the cached copy would otherwise keep the old setting until it expires
+		if app.config.redisCfg.enabled {
+			if err := app.cacheStorage.Users.Set(ctx, user); err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// checkContentVisibility responds with a not found error and returns false
+// when the current user isn't allowed to see author's content, the same way
+// postsContextMiddleware hides their posts.
+func (app *application) checkContentVisibility(w http.ResponseWriter, r *http.Request, author *store.User) bool {
+	visible, err := app.store.Users.CanViewContent(r.Context(), getUserFromContext(r).ID, author.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	if !visible {
+		app.notFoundError(w, r, fmt.Errorf("user %d's content isn't visible", author.ID))
+		return false
+	}
+
+	return true
+}
+
 func getUserFromContext(r *http.Request) *store.User {
 	user, _ := r.Context().Value(userCtx).(*store.User)
 
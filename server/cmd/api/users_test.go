@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/iykeevans/go-social/server/internal/ratelimiter"
+	"github.com/iykeevans/go-social/server/internal/store"
 	"github.com/iykeevans/go-social/server/internal/store/cache"
 	"github.com/stretchr/testify/mock"
 )
@@ -106,3 +112,204 @@ func TestGetUser(t *testing.T) {
 		mockCacheStore.Calls = nil // Reset mock expectations
 	})
 }
+
+type testInvitationUserStore struct {
+	*store.MockUserStore
+	invitations map[string]string
+}
+
+func (s *testInvitationUserStore) ReissueInvitation(ctx context.Context, email, token string, exp time.Duration, mail func(*store.User) (*store.OutboxMessage, error)) (*store.User, error) {
+	if email != "inactive@example.com" {
+		return nil, store.ErrNotFound
+	}
+
+	user := &store.User{ID: readerID, Username: "inactive", Email: email}
+	if _, err := mail(user); err != nil {
+		return nil, err
+	}
+
+	s.invitations[email] = token
+
+	return user, nil
+}
+
+func TestResendActivation(t *testing.T) {
+	cfg := config{
+		activation: activationConfig{
+			resendLimiter: ratelimiter.Config{
+				RequestsPerTimeFrame: 2,
+				TimeFrame:            time.Minute,
+			},
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	users := &testInvitationUserStore{invitations: make(map[string]string)}
+	app.store.Users = users
+	mux := app.mount()
+
+	resend := func(email string) int {
+		body := `{"email": "` + email + `"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users/activation/resend", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should reissue the invitation of an inactive account", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend("Inactive@example.com"))
+
+		token, ok := users.invitations["inactive@example.com"]
+		if !ok {
+			t.Fatal("expected the invitation to be reissued")
+		}
+
+		// only the hash of the token is stored
+		if len(token) != 64 {
+			t.Errorf("expected a hashed token, got %q", token)
+		}
+	})
+
+	t.Run("should respond the same for an unknown email", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend("nobody@example.com"))
+	})
+
+	t.Run("should throttle resends per email", func(t *testing.T) {
+		checkResponseCode(t, http.StatusAccepted, resend("inactive@example.com"))
+		checkResponseCode(t, http.StatusTooManyRequests, resend("inactive@example.com"))
+	})
+}
+
+// testProfileUserStore knows every user but 99.
+type testProfileUserStore struct {
+	*store.MockUserStore
+}
+
+func (s *testProfileUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	if userID == 99 {
+		return nil, store.ErrNotFound
+	}
+
+	return &store.User{ID: userID}, nil
+}
+
+func (s *testProfileUserStore) GetProfileStats(ctx context.Context, userID int64) (*store.UserStats, error) {
+	return &store.UserStats{Posts: 3, Followers: 2, Following: 1}, nil
+}
+
+func TestGetUserProfile(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testProfileUserStore{}
+	mux := app.mount()
+
+	token := generateTestToken(t, app, readerID)
+
+	t.Run("should include the profile counts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data store.UserProfile `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := store.UserStats{Posts: 3, Followers: 2, Following: 1}
+		if res.Data.Stats != expected {
+			t.Errorf("expected stats %+v, got %+v", expected, res.Data.Stats)
+		}
+	})
+
+	t.Run("should not find unknown users", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/99", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+// testPrivacyUserStore makes the author's account private, with no
+// approved followers.
+type testPrivacyUserStore struct {
+	*store.MockUserStore
+}
+
+func (s *testPrivacyUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	return &store.User{ID: userID, IsPrivate: userID == authorID}, nil
+}
+
+func (s *testPrivacyUserStore) CanViewContent(ctx context.Context, viewerID, authorID int64) (bool, error) {
+	return viewerID == authorID, nil
+}
+
+type testFollowRequestStore struct {
+	*store.MockFollowerStore
+	requested []int64
+}
+
+func (s *testFollowRequestStore) RequestFollow(ctx context.Context, requesterID, userID int64) error {
+	s.requested = append(s.requested, userID)
+	return nil
+}
+
+func TestPrivateAccounts(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Users = &testPrivacyUserStore{}
+	app.store.Posts = &testPostStore{}
+	followers := &testFollowRequestStore{}
+	app.store.Followers = followers
+	mux := app.mount()
+
+	tests := []struct {
+		name     string
+		userID   int64
+		method   string
+		path     string
+		expected int
+	}{
+		{"should hide posts from non followers", readerID, http.MethodGet, "/v1/posts/1", http.StatusNotFound},
+		{"should hide comments from non followers", readerID, http.MethodGet, "/v1/posts/1/comments", http.StatusNotFound},
+		{"should hide the post listing from non followers", readerID, http.MethodGet, "/v1/users/1/posts", http.StatusNotFound},
+		{"should hide connections from non followers", readerID, http.MethodGet, "/v1/users/1/followers", http.StatusNotFound},
+		{"should show the profile to everyone", readerID, http.MethodGet, "/v1/users/1", http.StatusOK},
+		{"should show posts to their author", authorID, http.MethodGet, "/v1/posts/1", http.StatusOK},
+		{"should request to follow private accounts", readerID, http.MethodPut, "/v1/users/1/follow", http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+generateTestToken(t, app, tt.userID))
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.expected, rr.Code)
+		})
+	}
+
+	if len(followers.requested) != 1 || followers.requested[0] != authorID {
+		t.Errorf("expected a follow request to user %d, got %v", authorID, followers.requested)
+	}
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_outbox_messages_status;
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+DROP TABLE IF EXISTS outbox_messages;
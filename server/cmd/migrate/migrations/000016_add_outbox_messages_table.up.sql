@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id bigserial PRIMARY KEY,
+    template varchar(255) NOT NULL,
+    username varchar(255) NOT NULL,
+    email citext NOT NULL,
+    -- data is discarded once the message is sent, or purged a while after
+    -- it's dead lettered
+    data jsonb DEFAULT '{}',
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    attempts int NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    sent_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_status ON outbox_messages (status);
@@ -0,0 +1,5 @@
+ALTER TABLE
+    outbox_messages DROP COLUMN locale;
+
+ALTER TABLE
+    users DROP COLUMN language;
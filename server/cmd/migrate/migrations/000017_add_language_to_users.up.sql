@@ -0,0 +1,9 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN language varchar(35) NOT NULL DEFAULT 'en';
+
+ALTER TABLE
+    outbox_messages
+ADD
+    COLUMN locale varchar(35) NOT NULL DEFAULT 'en';
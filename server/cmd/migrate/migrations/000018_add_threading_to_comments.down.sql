@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_comments_post_id_created_at;
+DROP INDEX IF EXISTS idx_comments_parent_id;
+
+ALTER TABLE
+    comments DROP COLUMN deleted_at,
+    DROP COLUMN updated_at,
+    DROP COLUMN depth,
+    DROP COLUMN parent_id;
@@ -0,0 +1,13 @@
+ALTER TABLE
+    comments
+ADD
+    COLUMN parent_id bigint REFERENCES comments (id),
+ADD
+    COLUMN depth int NOT NULL DEFAULT 0,
+ADD
+    COLUMN updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+ADD
+    COLUMN deleted_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at ON comments (post_id, created_at, id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS trending_tags (
+    tag varchar(100) PRIMARY KEY,
+    posts int NOT NULL,
+    authors int NOT NULL,
+    refreshed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    version int NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    tags varchar(100) [],
+    editor_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    UNIQUE (post_id, version)
+);
+
+-- existing posts start their history at their current version. Anyone
+-- allowed to edit a post may have made its later versions, so only the
+-- author of an unedited post is known to be its editor.
+INSERT INTO
+    post_revisions (post_id, version, title, content, tags, editor_id, created_at)
+SELECT
+    id,
+    COALESCE(version, 0),
+    title,
+    content,
+    tags,
+    CASE
+        WHEN COALESCE(version, 0) = 0 THEN user_id
+    END,
+    updated_at
+FROM
+    posts;
@@ -0,0 +1,8 @@
+ALTER TABLE
+    comments DROP CONSTRAINT IF EXISTS fk_comments_post_id;
+
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+
+ALTER TABLE
+    posts DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,28 @@
+ALTER TABLE
+    posts
+ADD
+    COLUMN deleted_at timestamp(0) with time zone,
+ADD
+    COLUMN deleted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at)
+WHERE
+    deleted_at IS NOT NULL;
+
+-- posts used to be hard deleted without their comments
+DELETE FROM
+    comments c
+WHERE
+    NOT EXISTS (
+        SELECT
+            1
+        FROM
+            posts p
+        WHERE
+            p.id = c.post_id
+    );
+
+ALTER TABLE
+    comments
+ADD
+    CONSTRAINT fk_comments_post_id FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS moderation_log;
+DROP FUNCTION IF EXISTS moderation_log_immutable;
+DROP TABLE IF EXISTS user_suspensions;
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE
+    comments DROP COLUMN hidden_at;
+
+ALTER TABLE
+    posts DROP COLUMN hidden_at;
@@ -0,0 +1,81 @@
+ALTER TABLE
+    posts
+ADD
+    COLUMN hidden_at timestamp(0) with time zone;
+
+ALTER TABLE
+    comments
+ADD
+    COLUMN hidden_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS reports (
+    id bigserial PRIMARY KEY,
+    reporter_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    comment_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+    reason varchar(32) NOT NULL CHECK (
+        reason IN (
+            'spam',
+            'harassment',
+            'hate',
+            'violence',
+            'sexual',
+            'misinformation',
+            'other'
+        )
+    ),
+    details text NOT NULL DEFAULT '',
+    status varchar(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    assignee_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    resolution varchar(16) CHECK (resolution IN ('dismiss', 'hide', 'warn', 'suspend')),
+    resolved_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- a user can only have one open report per post or comment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_target ON reports (reporter_id, post_id, COALESCE(comment_id, 0))
+WHERE
+    status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_reports_status_created_at ON reports (status, created_at);
+
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason text NOT NULL,
+    suspended_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    -- suspensions without an expiry never end
+    expires_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_suspensions_user_id ON user_suspensions (user_id);
+
+-- the log outlives what it refers to, so it holds plain ids rather than
+-- foreign keys that would update or delete its rows
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id bigserial PRIMARY KEY,
+    moderator_id bigint NOT NULL,
+    action varchar(32) NOT NULL,
+    report_id bigint,
+    post_id bigint,
+    comment_id bigint,
+    subject_id bigint,
+    note text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_subject_id ON moderation_log (subject_id);
+
+CREATE OR REPLACE FUNCTION moderation_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'moderation_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER moderation_log_immutable BEFORE
+UPDATE
+    OR DELETE ON moderation_log FOR EACH ROW EXECUTE FUNCTION moderation_log_immutable();
+
+CREATE TRIGGER moderation_log_no_truncate BEFORE TRUNCATE ON moderation_log FOR EACH STATEMENT EXECUTE FUNCTION moderation_log_immutable();
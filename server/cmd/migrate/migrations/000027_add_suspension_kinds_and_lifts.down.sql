@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_user_suspensions_expires_at;
+DROP INDEX IF EXISTS idx_user_suspensions_user_id;
+
+ALTER TABLE
+    user_suspensions DROP COLUMN lifted_by,
+    DROP COLUMN lifted_at,
+    DROP COLUMN kind;
+
+CREATE INDEX IF NOT EXISTS idx_user_suspensions_user_id ON user_suspensions (user_id);
@@ -0,0 +1,18 @@
+ALTER TABLE
+    user_suspensions
+ADD
+    COLUMN kind varchar(16) NOT NULL DEFAULT 'suspension' CHECK (kind IN ('suspension', 'ban')),
+ADD
+    COLUMN lifted_at timestamp(0) with time zone,
+ADD
+    COLUMN lifted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+DROP INDEX IF EXISTS idx_user_suspensions_user_id;
+
+CREATE INDEX IF NOT EXISTS idx_user_suspensions_user_id ON user_suspensions (user_id)
+WHERE
+    lifted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_suspensions_expires_at ON user_suspensions (expires_at)
+WHERE
+    lifted_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS role_changes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    old_role_id bigint NOT NULL REFERENCES roles (id),
+    new_role_id bigint NOT NULL REFERENCES roles (id),
+    changed_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_changes_user_id ON role_changes (user_id, created_at);
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    name varchar(64) PRIMARY KEY,
+    description text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id bigint NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission varchar(64) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+
+    PRIMARY KEY (role_id, permission)
+);
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('posts:update:any', 'Update other users posts'),
+    ('posts:delete:any', 'Delete other users posts'),
+    ('posts:restore:any', 'Restore other users deleted posts'),
+    ('comments:moderate', 'Update and delete other users comments'),
+    ('reports:review', 'Work through the moderation queue'),
+    ('users:suspend', 'Suspend, ban and reinstate users'),
+    ('roles:manage', 'Create roles and change users roles'),
+    ('outbox:manage', 'Inspect and retry outgoing emails');
+
+-- the built in roles keep what their levels used to allow
+INSERT INTO
+    role_permissions (role_id, permission)
+SELECT
+    r.id, p.name
+FROM
+    roles r
+    JOIN permissions p ON p.name IN (
+        'posts:update:any',
+        'comments:moderate',
+        'reports:review'
+    )
+WHERE
+    r.name = 'moderator';
+
+INSERT INTO
+    role_permissions (role_id, permission)
+SELECT
+    r.id, p.name
+FROM
+    roles r
+    CROSS JOIN permissions p
+WHERE
+    r.name = 'admin';
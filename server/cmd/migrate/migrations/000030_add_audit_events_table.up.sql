@@ -0,0 +1,45 @@
+-- actors and targets aren't foreign keys so that events outlive the rows
+-- they're about
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    action varchar(64) NOT NULL,
+    target_type varchar(32) NOT NULL DEFAULT '',
+    target_id bigint,
+    ip varchar(64) NOT NULL DEFAULT '',
+    request_id varchar(128) NOT NULL DEFAULT '',
+    metadata jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_immutable BEFORE
+UPDATE
+    OR DELETE ON audit_events FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();
+
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events FOR EACH STATEMENT EXECUTE FUNCTION audit_events_immutable();
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('audit:read', 'Query and export the audit log');
+
+INSERT INTO
+    role_permissions (role_id, permission)
+SELECT
+    id, 'audit:read'
+FROM
+    roles
+WHERE
+    name = 'admin';
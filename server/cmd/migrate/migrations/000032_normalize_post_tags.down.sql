@@ -0,0 +1 @@
+-- the tags as they were written before being normalized aren't kept
@@ -0,0 +1,28 @@
+-- tags written before they were normalized are case-folded, stripped of a
+-- leading '#' and deduplicated the same way NormalizeTags does. Invalid tags
+-- are dropped and only the first 10 are kept.
+UPDATE
+    posts p
+SET
+    tags = ARRAY(
+        SELECT
+            t.tag
+        FROM
+            (
+                SELECT
+                    lower(regexp_replace(trim(raw.tag), '^#', '')) AS tag,
+                    MIN(raw.position) AS position
+                FROM
+                    unnest(p.tags) WITH ORDINALITY AS raw(tag, position)
+                GROUP BY
+                    1
+            ) t
+        WHERE
+            t.tag ~ '^[[:alnum:]_-]{1,32}$'
+        ORDER BY
+            t.position
+        LIMIT
+            10
+    )
+WHERE
+    p.tags IS NOT NULL;
@@ -2,24 +2,70 @@ package auth
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type JWTAuthenticator struct {
-	secret string
+	keys   map[string]*SigningKey
+	active *SigningKey
 	aud    string
 	iss    string
 }
 
+// NewJWTAuthenticator returns an authenticator that signs and validates
+// tokens with a single shared HS256 secret.
 func NewJWTAuthenticator(secret, aud, iss string) *JWTAuthenticator {
-	return &JWTAuthenticator{secret, iss, aud}
+	key := NewHMACKey("", secret)
+
+	return &JWTAuthenticator{
+		keys:   map[string]*SigningKey{key.ID: key},
+		active: key,
+		aud:    aud,
+		iss:    iss,
+	}
+}
+
+// NewJWTAuthenticatorWithKeys returns an authenticator that signs with the
+// key identified by activeKID and accepts tokens signed by any of keys, so
+// retiring keys keep validating until the tokens they signed expire.
+func NewJWTAuthenticatorWithKeys(keys []*SigningKey, activeKID, aud, iss string) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{
+		keys: make(map[string]*SigningKey, len(keys)),
+		aud:  aud,
+		iss:  iss,
+	}
+
+	for _, key := range keys {
+		if _, exists := a.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+
+		a.keys[key.ID] = key
+	}
+
+	active, ok := a.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeKID)
+	}
+
+	if !active.canSign() {
+		return nil, fmt.Errorf("active key %q has no private key", activeKID)
+	}
+
+	a.active = active
+
+	return a, nil
 }
 
 func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(a.active.Method, claims)
+	if a.active.ID != "" {
+		token.Header["kid"] = a.active.ID
+	}
 
-	tokenString, err := token.SignedString([]byte(a.secret))
+	tokenString, err := token.SignedString(a.active.private)
 	if err != nil {
 		return "", err
 	}
@@ -29,15 +75,51 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 
 func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
 		}
 
-		return []byte(a.secret), nil
+		return key.public, nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.aud),
-		jwt.WithIssuer(a.aud),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods(a.validMethods()),
 	)
 }
+
+func (a *JWTAuthenticator) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, key := range a.keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+
+	return set
+}
+
+func (a *JWTAuthenticator) validMethods() []string {
+	seen := make(map[string]bool)
+	methods := []string{}
+
+	for _, key := range a.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
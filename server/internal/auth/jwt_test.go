@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": 1,
+		"aud": "test",
+		"iss": "test",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func encodePEM(t *testing.T, blockType string, der []byte) []byte {
+	t.Helper()
+
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func TestJWTAuthenticatorKeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, err := ParseKey("old", encodePEM(t, "PRIVATE KEY", rsaDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := ParseKey("new", encodePEM(t, "PRIVATE KEY", edDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retiredKey, err := ParseKey("old", encodePEM(t, "PUBLIC KEY", rsaPubDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := NewJWTAuthenticatorWithKeys([]*SigningKey{oldKey}, "old", "test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldToken, err := before.GenerateToken(newTestClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := NewJWTAuthenticatorWithKeys([]*SigningKey{retiredKey, newKey}, "new", "test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should sign with the active key", func(t *testing.T) {
+		token, err := after.GenerateToken(newTestClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := after.ValidateToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.Header["kid"] != "new" || parsed.Method.Alg() != jwt.SigningMethodEdDSA.Alg() {
+			t.Errorf("expected EdDSA token with kid new, got %v %v", parsed.Method.Alg(), parsed.Header["kid"])
+		}
+	})
+
+	t.Run("should accept tokens signed by a retiring key", func(t *testing.T) {
+		if _, err := after.ValidateToken(oldToken); err != nil {
+			t.Errorf("expected retiring key to validate, got %v", err)
+		}
+	})
+
+	t.Run("should reject tokens signed by an unknown key", func(t *testing.T) {
+		other, err := NewJWTAuthenticatorWithKeys([]*SigningKey{newKey}, "new", "test", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := other.ValidateToken(oldToken); err == nil {
+			t.Error("expected token signed by a removed key to be rejected")
+		}
+	})
+
+	t.Run("should not sign with a public only key", func(t *testing.T) {
+		if _, err := NewJWTAuthenticatorWithKeys([]*SigningKey{retiredKey}, "old", "test", "test"); err == nil {
+			t.Error("expected an error for a public only active key")
+		}
+	})
+
+	t.Run("should publish only public keys", func(t *testing.T) {
+		set := after.JWKS()
+		if len(set.Keys) != 2 {
+			t.Fatalf("expected 2 keys, got %d", len(set.Keys))
+		}
+
+		if set.Keys[0].Kid != "new" || set.Keys[0].Kty != "OKP" || set.Keys[1].Kty != "RSA" {
+			t.Errorf("unexpected key set %+v", set.Keys)
+		}
+
+		if hmac := NewJWTAuthenticator("secret", "test", "test").JWKS(); len(hmac.Keys) != 0 {
+			t.Errorf("expected HMAC secret not to be published, got %+v", hmac.Keys)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key identified by its kid. Keys loaded from a public key
+// only can validate tokens but never sign them.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewHMACKey(kid, secret string) *SigningKey {
+	return &SigningKey{
+		ID:      kid,
+		Method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+}
+
+// ParseKey reads a PEM encoded RSA or Ed25519 key. Private keys (PKCS#8 or
+// PKCS#1) can sign and validate, public keys (PKIX) can only validate.
+func ParseKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM data found", kid)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+		}
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		switch k := parsed.(type) {
+		case *rsa.PublicKey:
+			return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, public: k}, nil
+		case ed25519.PublicKey:
+			return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, public: k}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key %q: unsupported key type %q", kid, block.Type)
+}
+
+// LoadKeys reads the keys listed in spec, a comma separated list of
+// kid=path pairs, e.g. "2025-01=/etc/keys/2025-01.pem,2024-07=/etc/keys/2024-07.pub".
+func LoadKeys(spec string) ([]*SigningKey, error) {
+	var keys []*SigningKey
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("malformed key entry %q", entry)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParseKey(kid, data)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return keys, nil
+}
+
+func (k *SigningKey) canSign() bool {
+	return k.private != nil
+}
+
+// jwk returns the public half of the key, HMAC secrets are never published.
+func (k *SigningKey) jwk() (JWK, bool) {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Kid: k.ID,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Kid: k.ID,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
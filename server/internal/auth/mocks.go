@@ -14,11 +14,17 @@ var testClaims = jwt.MapClaims{
 	"aud": "test-aud",
 	"iss": "test-aud",
 	"sub": int64(42),
+	"sid": "test-session",
 	"exp": time.Now().Add(time.Hour).Unix(),
 }
 
+// GenerateToken signs claims, or a token for user 42 when they're nil.
 func (a *TestAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims)
+	if claims == nil {
+		claims = testClaims
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(secret))
 }
@@ -29,6 +35,10 @@ func (a *TestAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	})
 }
 
+func (a *TestAuthenticator) JWKS() JWKSet {
+	return JWKSet{Keys: []JWK{}}
+}
+
 // type Authenticator interface {
 // 	GenerateToken(claims jwt.Claims) (string, error)
 // 	ValidateToken(token string) (*jwt.Token, error)
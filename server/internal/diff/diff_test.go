@@ -0,0 +1,35 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	t.Run("should keep common lines and mark the changes", func(t *testing.T) {
+		got := Lines("a\nb\nc", "a\nx\nc\nd")
+
+		want := []Line{
+			{Op: OpEqual, Text: "a"},
+			{Op: OpDelete, Text: "b"},
+			{Op: OpInsert, Text: "x"},
+			{Op: OpEqual, Text: "c"},
+			{Op: OpInsert, Text: "d"},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should diff against empty texts", func(t *testing.T) {
+		if got := Lines("", ""); len(got) != 0 {
+			t.Errorf("expected no lines, got %v", got)
+		}
+
+		want := []Line{{Op: OpInsert, Text: "a"}}
+		if got := Lines("", "a"); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileSinkClient writes every email as an .eml file to dir instead of
+// delivering it, which is handy for local development.
+type fileSinkClient struct {
+	fromEmail string
+	dir       string
+}
+
+func NewFileSinkClient(dir, fromEmail string) (*fileSinkClient, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &fileSinkClient{
+		fromEmail: fromEmail,
+		dir:       dir,
+	}, nil
+}
+
+func (m *fileSinkClient) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	message := newMessage(m.fromEmail, username, email, rendered)
+
+	name := fmt.Sprintf(
+		"%s-%s-%s.eml",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		strings.TrimSuffix(templateFile, filepath.Ext(templateFile)),
+		sanitizeFileName(email),
+	)
+
+	f, err := os.Create(filepath.Join(m.dir, name))
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	if _, err := message.WriteTo(f); err != nil {
+		return -1, err
+	}
+
+	return 200, nil
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -1,16 +1,102 @@
 package mailer
 
-import "embed"
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	"text/template"
+)
 
 const (
-	FromName            = "Go Social"
-	maxRetries          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName               = "Go Social"
+	DefaultLocale          = "en"
+	UserWelcomeTemplate    = "user_invitation.tmpl"
+	PasswordResetTemplate  = "password_reset.tmpl"
+	ContentWarningTemplate = "content_warning.tmpl"
+
+	layoutTemplate = "layout.tmpl"
 )
 
 //go:embed "templates"
 var FS embed.FS
 
 type Client interface {
-	Send(templateFile, username, email string, data any, isSandbox bool) (int, error)
+	Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error)
+}
+
+// content is a rendered template with a plain text and an HTML alternative.
+type content struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// render executes templateFile, in the variant closest to locale, inside the
+// shared layout. Every template defines a subject, a text and an html block;
+// the html block is escaped by html/template.
+func render(templateFile, locale string, data any) (*content, error) {
+	file, locale := localize(templateFile, locale)
+	files := []string{"templates/" + layoutTemplate, "templates/" + file}
+
+	layoutData := struct {
+		Locale string
+		Data   any
+	}{Locale: locale, Data: data}
+
+	textTmpl, err := template.ParseFS(FS, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	text := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(text, "layout_text", layoutData); err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmltemplate.ParseFS(FS, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	html := new(bytes.Buffer)
+	if err := htmlTmpl.ExecuteTemplate(html, "layout_html", layoutData); err != nil {
+		return nil, err
+	}
+
+	return &content{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// localize returns the most specific variant of templateFile that exists for
+// locale, e.g. user_invitation.fr-CA.tmpl, then user_invitation.fr.tmpl, then
+// user_invitation.tmpl, along with the locale it was written for.
+func localize(templateFile, locale string) (string, string) {
+	name := strings.TrimSuffix(templateFile, ".tmpl")
+
+	candidates := []string{}
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			candidates = append(candidates, lang)
+		}
+	}
+
+	for _, candidate := range candidates {
+		file := name + "." + strings.ToLower(candidate) + ".tmpl"
+		if _, err := fs.Stat(FS, "templates/"+file); err == nil {
+			return file, candidate
+		}
+	}
+
+	return templateFile, DefaultLocale
 }
@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]any{
+		"Username":      "<bob>",
+		"ActivationURL": "http://localhost:5173/confirm/abc?x=1&y=2",
+	}
+
+	t.Run("should render a text and an escaped html part", func(t *testing.T) {
+		rendered, err := render(UserWelcomeTemplate, "en", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rendered.Subject != "Finish Registration with Go Social" {
+			t.Errorf("unexpected subject %q", rendered.Subject)
+		}
+
+		if !strings.Contains(rendered.Text, "Hi <bob>,") || !strings.Contains(rendered.Text, "abc?x=1&y=2") {
+			t.Errorf("expected unescaped values in the text part, got %q", rendered.Text)
+		}
+
+		if !strings.Contains(rendered.HTML, "Hi &lt;bob&gt;,") || !strings.Contains(rendered.HTML, `<html lang="en">`) {
+			t.Errorf("expected escaped values inside the layout in the html part, got %q", rendered.HTML)
+		}
+	})
+
+	t.Run("should pick the closest locale variant", func(t *testing.T) {
+		cases := map[string]string{
+			"fr":    "Finalisez votre inscription à Go Social",
+			"fr-CA": "Finalisez votre inscription à Go Social",
+			"de":    "Finish Registration with Go Social",
+			"":      "Finish Registration with Go Social",
+		}
+
+		for locale, subject := range cases {
+			rendered, err := render(UserWelcomeTemplate, locale, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rendered.Subject != subject {
+				t.Errorf("locale %q: expected subject %q, got %q", locale, subject, rendered.Subject)
+			}
+		}
+	})
+}
+
+func TestRenderContentWarning(t *testing.T) {
+	data := map[string]any{
+		"Username":    "bob",
+		"ContentType": "comment",
+		"Note":        "<b>be nice</b>",
+	}
+
+	rendered, err := render(ContentWarningTemplate, "en", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rendered.Subject != "A warning about your Go Social comment" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+
+	if !strings.Contains(rendered.Text, "<b>be nice</b>") || !strings.Contains(rendered.HTML, "&lt;b&gt;be nice&lt;/b&gt;") {
+		t.Errorf("expected the moderator's note in both parts, escaped in html, got %q and %q", rendered.Text, rendered.HTML)
+	}
+
+	rendered, err = render(ContentWarningTemplate, "fr", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rendered.Subject != "Un avertissement concernant votre commentaire Go Social" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+}
+
+func TestFileSinkClient(t *testing.T) {
+	dir := t.TempDir()
+
+	client, err := NewFileSinkClient(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]any{
+		"Username":      "bob",
+		"ActivationURL": "http://localhost:5173/confirm/abc",
+	}
+
+	if _, err := client.Send(UserWelcomeTemplate, "en", "bob", "bob@example.com", data, true); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), "-user_invitation-bob@example.com.eml") {
+		t.Fatalf("expected one email file, got %v", files)
+	}
+
+	message, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(message), "multipart/alternative") {
+		t.Errorf("expected a multipart email, got %s", message)
+	}
+}
@@ -1,9 +1,7 @@
 package mailer
 
 import (
-	"bytes"
 	"errors"
-	"text/template"
 
 	gomail "gopkg.in/mail.v2"
 )
@@ -24,31 +22,13 @@ func NewMailTrapClient(apiKey, fromEmail string) (mailtrapClient, error) {
 	}, nil
 }
 
-func (m mailtrapClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
-	// Template parsing and building
-	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+func (m mailtrapClient) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := render(templateFile, locale, data)
 	if err != nil {
 		return -1, err
 	}
 
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return -1, err
-	}
-
-	body := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(body, "body", data)
-	if err != nil {
-		return -1, err
-	}
-
-	message := gomail.NewMessage()
-	message.SetHeader("From", m.fromEmail)
-	message.SetHeader("To", email)
-	message.SetHeader("Subject", subject.String())
-
-	message.AddAlternative("text/html", body.String())
+	message := newMessage(m.fromEmail, username, email, rendered)
 
 	// Set up the SMTP dialer
 	dialer := gomail.NewDialer("live.smtp.mailtrap.io", 587, "api", m.apiKey)
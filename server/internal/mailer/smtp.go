@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+
+	gomail "gopkg.in/mail.v2"
+)
+
+const (
+	TLSModeStartTLS = "starttls"
+	TLSModeImplicit = "tls"
+	TLSModeNone     = "none"
+
+	AuthPlain = "plain"
+	AuthLogin = "login"
+	AuthNone  = "none"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// TLSMode is one of starttls (default), tls for implicit TLS or none.
+	TLSMode string
+	// Auth is one of plain (default), login or none.
+	Auth string
+}
+
+type smtpClient struct {
+	fromEmail string
+	cfg       SMTPConfig
+}
+
+func NewSMTPClient(cfg SMTPConfig, fromEmail string) (*smtpClient, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	if cfg.TLSMode == "" {
+		cfg.TLSMode = TLSModeStartTLS
+	}
+
+	if cfg.Auth == "" {
+		cfg.Auth = AuthPlain
+	}
+
+	switch cfg.TLSMode {
+	case TLSModeStartTLS, TLSModeImplicit, TLSModeNone:
+	default:
+		return nil, fmt.Errorf("unsupported smtp tls mode %q", cfg.TLSMode)
+	}
+
+	switch cfg.Auth {
+	case AuthPlain, AuthLogin:
+		if cfg.Username == "" {
+			return nil, fmt.Errorf("smtp username is required for %s auth", cfg.Auth)
+		}
+	case AuthNone:
+	default:
+		return nil, fmt.Errorf("unsupported smtp auth %q", cfg.Auth)
+	}
+
+	return &smtpClient{
+		fromEmail: fromEmail,
+		cfg:       cfg,
+	}, nil
+}
+
+func (m *smtpClient) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	message := newMessage(m.fromEmail, username, email, rendered)
+
+	dialer := &gomail.Dialer{
+		Host:         m.cfg.Host,
+		Port:         m.cfg.Port,
+		RetryFailure: false,
+	}
+
+	switch m.cfg.TLSMode {
+	case TLSModeImplicit:
+		dialer.SSL = true
+	case TLSModeStartTLS:
+		dialer.StartTLSPolicy = gomail.MandatoryStartTLS
+	case TLSModeNone:
+		dialer.StartTLSPolicy = gomail.NoStartTLS
+	}
+
+	switch m.cfg.Auth {
+	case AuthPlain:
+		dialer.Auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	case AuthLogin:
+		dialer.Auth = &loginAuth{username: m.cfg.Username, password: m.cfg.Password}
+	}
+
+	if err := dialer.DialAndSend(message); err != nil {
+		return -1, err
+	}
+
+	return 200, nil
+}
+
+// newMessage builds a multipart/alternative message, the HTML part comes last
+// so clients that can display it prefer it over the plain text part.
+func newMessage(fromEmail, username, email string, rendered *content) *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetAddressHeader("From", fromEmail, FromName)
+	message.SetAddressHeader("To", email, username)
+	message.SetHeader("Subject", rendered.Subject)
+
+	message.SetBody("text/plain", rendered.Text)
+	message.AddAlternative("text/html", rendered.HTML)
+
+	return message
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp doesn't ship.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditLogin                  = "auth.login"
+	AuditLoginFailed            = "auth.login_failed"
+	AuditTokenRefreshed         = "auth.token_refreshed"
+	AuditTokenReused            = "auth.token_reused"
+	AuditLogout                 = "auth.logout"
+	AuditPasswordResetRequested = "auth.password_reset_requested"
+	AuditPasswordReset          = "auth.password_reset"
+	AuditPostDeleted            = "post.deleted"
+	AuditPostRestored           = "post.restored"
+	AuditReportAssigned         = "report.assigned"
+	AuditReportResolved         = "report.resolved"
+	AuditUserSuspended          = "user.suspended"
+	AuditSuspensionLifted       = "user.suspension_lifted"
+	AuditRoleCreated            = "role.created"
+	AuditRoleChanged            = "user.role_changed"
+)
+
+// Kinds of audit event targets
+const (
+	AuditTargetUser   = "user"
+	AuditTargetPost   = "post"
+	AuditTargetReport = "report"
+	AuditTargetRole   = "role"
+)
+
+// AuditExportTimeout bounds how long an export may stream for.
+var AuditExportTimeout = time.Minute
+
+// AuditEvent records who did what to which resource, and from where.
+// ActorID is unset when nobody could be authenticated, like for logins with
+// an unknown email.
+type AuditEvent struct {
+	ID         int64          `json:"id"`
+	ActorID    *int64         `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   *int64         `json:"target_id"`
+	IP         string         `json:"ip"`
+	RequestID  string         `json:"request_id"`
+	Metadata   map[string]any `json:"metadata"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type AuditQuery struct {
+	ActorID    int64      `json:"actor_id" validate:"gte=0"`
+	TargetType string     `json:"target_type" validate:"omitempty,oneof=user post report role"`
+	TargetID   int64      `json:"target_id" validate:"gte=0"`
+	Action     string     `json:"action" validate:"max=64"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	Limit      int        `json:"limit" validate:"gte=1,lte=100"`
+	Offset     int        `json:"offset" validate:"gte=0"`
+}
+
+func (q AuditQuery) Parse(r *http.Request) (AuditQuery, error) {
+	qs := r.URL.Query()
+
+	if actor := qs.Get("actor_id"); actor != "" {
+		id, err := strconv.ParseInt(actor, 10, 64)
+		if err != nil {
+			return q, err
+		}
+
+		q.ActorID = id
+	}
+
+	if targetType := qs.Get("target_type"); targetType != "" {
+		q.TargetType = targetType
+	}
+
+	if target := qs.Get("target_id"); target != "" {
+		id, err := strconv.ParseInt(target, 10, 64)
+		if err != nil {
+			return q, err
+		}
+
+		q.TargetID = id
+	}
+
+	if action := qs.Get("action"); action != "" {
+		q.Action = action
+	}
+
+	if from := qs.Get("from"); from != "" {
+		t, err := parseTime(from)
+		if err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+
+		q.From = &t
+	}
+
+	if to := qs.Get("to"); to != "" {
+		t, err := parseTime(to)
+		if err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+
+		q.To = &t
+	}
+
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return q, errors.New("from must be before to")
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+// Create appends an event to the audit log. The log is append only, the
+// database refuses updates and deletes.
+func (s *AuditStore) Create(ctx context.Context, e *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return createAuditEvent(ctx, tx, e)
+	})
+}
+
+// createAuditEvent appends an event to the audit log within tx, so that it's
+// only recorded along with the action it describes.
+func createAuditEvent(ctx context.Context, tx *sql.Tx, e *AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, ip, request_id, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	metadata := []byte("{}")
+	if len(e.Metadata) > 0 {
+		encoded, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+
+		metadata = encoded
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		e.ActorID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.IP,
+		e.RequestID,
+		metadata,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+// List returns a page of the events matching q, most recent first.
+func (s *AuditStore) List(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	events := []AuditEvent{}
+	err := s.query(ctx, q, q.Limit, func(e *AuditEvent) error {
+		events = append(events, *e)
+		return nil
+	})
+
+	return events, err
+}
+
+// Export calls fn with every event matching q, most recent first, ignoring
+// its limit and offset. Events are streamed rather than loaded all at once.
+func (s *AuditStore) Export(ctx context.Context, q AuditQuery, fn func(*AuditEvent) error) error {
+	ctx, cancel := context.WithTimeout(ctx, AuditExportTimeout)
+	defer cancel()
+
+	q.Offset = 0
+
+	return s.query(ctx, q, nil, fn)
+}
+
+// query runs q, returning every matching event when limit is nil.
+func (s *AuditStore) query(ctx context.Context, q AuditQuery, limit any, fn func(*AuditEvent) error) error {
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, ip, request_id, metadata, created_at
+		FROM audit_events
+		WHERE ($1 = 0 OR actor_id = $1)
+			AND ($2 = '' OR target_type = $2)
+			AND ($3 = 0 OR target_id = $3)
+			AND ($4 = '' OR action = $4)
+			AND ($5::timestamptz IS NULL OR created_at >= $5)
+			AND ($6::timestamptz IS NULL OR created_at < $6)
+		ORDER BY id DESC
+		LIMIT $7 OFFSET $8
+	`
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		q.ActorID,
+		q.TargetType,
+		q.TargetID,
+		q.Action,
+		q.From,
+		q.To,
+		limit,
+		q.Offset,
+	)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e        AuditEvent
+			metadata []byte
+		)
+
+		err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&e.RequestID,
+			&metadata,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(metadata, &e.Metadata); err != nil {
+			return err
+		}
+
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
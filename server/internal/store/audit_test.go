@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAuditStore(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	audit := &AuditStore{db}
+
+	// events can't be deleted, an action of their own keeps them apart from
+	// earlier runs
+	suffix := time.Now().UnixNano()
+	action := fmt.Sprintf("test.%d", suffix)
+	actorID, otherID, postID := suffix, suffix+1, int64(1)
+
+	events := []*AuditEvent{
+		{ActorID: &actorID, Action: action, TargetType: AuditTargetPost, TargetID: &postID, IP: "203.0.113.1", RequestID: "req-1", Metadata: map[string]any{"reason": "spam"}},
+		{ActorID: &otherID, Action: action, TargetType: AuditTargetUser, TargetID: &actorID},
+		{Action: action, IP: "203.0.113.2"},
+	}
+
+	for _, e := range events {
+		if err := audit.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+
+		if e.ID == 0 || e.CreatedAt.IsZero() {
+			t.Fatalf("expected the event to be saved, got %+v", e)
+		}
+	}
+
+	ids := func(events []AuditEvent) []int64 {
+		ids := []int64{}
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+
+		return ids
+	}
+
+	t.Run("should list matching events, most recent first", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			q        AuditQuery
+			expected []int64
+		}{
+			{"action", AuditQuery{Action: action, Limit: 10}, []int64{events[2].ID, events[1].ID, events[0].ID}},
+			{"page", AuditQuery{Action: action, Limit: 1, Offset: 1}, []int64{events[1].ID}},
+			{"actor", AuditQuery{Action: action, ActorID: actorID, Limit: 10}, []int64{events[0].ID}},
+			{"target", AuditQuery{Action: action, TargetType: AuditTargetUser, TargetID: actorID, Limit: 10}, []int64{events[1].ID}},
+		}
+
+		for _, tt := range tests {
+			listed, err := audit.List(ctx, tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ids(listed); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("%s: expected events %v, got %v", tt.name, tt.expected, got)
+			}
+		}
+
+		from := time.Now().Add(time.Hour)
+
+		listed, err := audit.List(ctx, AuditQuery{Action: action, From: &from, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(listed) != 0 {
+			t.Errorf("expected no events from %v, got %v", from, ids(listed))
+		}
+	})
+
+	t.Run("should read events back as recorded", func(t *testing.T) {
+		listed, err := audit.List(ctx, AuditQuery{Action: action, ActorID: actorID, Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := listed[0]
+		if *e.TargetID != postID || e.IP != "203.0.113.1" || e.RequestID != "req-1" || e.Metadata["reason"] != "spam" {
+			t.Errorf("expected the event as created, got %+v", e)
+		}
+	})
+
+	t.Run("should export every matching event regardless of the page", func(t *testing.T) {
+		var exported []AuditEvent
+
+		err := audit.Export(ctx, AuditQuery{Action: action, Limit: 1, Offset: 2}, func(e *AuditEvent) error {
+			exported = append(exported, *e)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, expected := ids(exported), []int64{events[2].ID, events[1].ID, events[0].ID}; fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("expected events %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("should not record events of rolled back transactions", func(t *testing.T) {
+		rolledBack := fmt.Sprintf("test.%d", time.Now().UnixNano())
+		failure := errors.New("mutation failed")
+
+		err := withTx(db, ctx, func(tx *sql.Tx) error {
+			if err := createAuditEvent(ctx, tx, &AuditEvent{Action: rolledBack}); err != nil {
+				return err
+			}
+
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("expected the transaction to fail, got %v", err)
+		}
+
+		listed, err := audit.List(ctx, AuditQuery{Action: rolledBack, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(listed) != 0 {
+			t.Errorf("expected no events, got %v", ids(listed))
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// BlockedUser is a user appearing in someone's block or mute list.
+type BlockedUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+}
+
+type BlocksStore struct {
+	db *sql.DB
+}
+
+// Block blocks blockedID on behalf of userID. Whatever follows or follow
+// requests exist between the two users are removed, in both directions.
+func (s *BlocksStore) Block(ctx context.Context, userID, blockedID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO user_blocks (user_id, blocked_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+
+		if _, err := tx.ExecContext(ctx, query, userID, blockedID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+
+			return err
+		}
+
+		query = `
+			DELETE FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+		`
+
+		if _, err := tx.ExecContext(ctx, query, userID, blockedID); err != nil {
+			return err
+		}
+
+		query = `
+			DELETE FROM follow_requests
+			WHERE (user_id = $1 AND requester_id = $2) OR (user_id = $2 AND requester_id = $1)
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID, blockedID)
+		return err
+	})
+}
+
+func (s *BlocksStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	query := `DELETE FROM user_blocks WHERE user_id = $1 AND blocked_id = $2`
+
+	return s.delete(ctx, query, userID, blockedID)
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (s *BlocksStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var blocked bool
+
+	if err := s.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+func (s *BlocksStore) GetBlocked(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.user_id = $1
+		ORDER BY b.created_at DESC, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return s.list(ctx, query, userID, q)
+}
+
+// Mute hides mutedID's posts from userID's feed without them knowing.
+func (s *BlocksStore) Mute(ctx context.Context, userID, mutedID int64) error {
+	query := `
+		INSERT INTO user_mutes (user_id, muted_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, mutedID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *BlocksStore) Unmute(ctx context.Context, userID, mutedID int64) error {
+	query := `DELETE FROM user_mutes WHERE user_id = $1 AND muted_id = $2`
+
+	return s.delete(ctx, query, userID, mutedID)
+}
+
+func (s *BlocksStore) GetMuted(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, m.created_at
+		FROM user_mutes m
+		JOIN users u ON u.id = m.muted_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at DESC, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return s.list(ctx, query, userID, q)
+}
+
+func (s *BlocksStore) delete(ctx context.Context, query string, userID, otherID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, otherID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *BlocksStore) list(ctx context.Context, query string, userID int64, q ConnectionsQuery) ([]BlockedUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []BlockedUser{}
+	for rows.Next() {
+		var u BlockedUser
+
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
@@ -27,6 +27,7 @@ func (m *MockUserStore) Set(ctx context.Context, user *store.User) error {
 	return args.Error(0)
 }
 
-func (m *MockUserStore) Delete(ctx context.Context, userID int64) {
-	m.Called(userID)
+func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
 }
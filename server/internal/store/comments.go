@@ -3,33 +3,79 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
 )
 
 type Comment struct {
 	ID        int64  `json:"id"`
 	PostID    int64  `json:"post_id"`
+	ParentID  *int64 `json:"parent_id"`
+	Depth     int    `json:"depth"`
 	UserID    int64  `json:"user_id"`
 	Content   string `json:"content"`
 	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Deleted   bool   `json:"deleted"`
+	Hidden    bool   `json:"hidden"`
 	User      User   `json:"user"`
 }
 
+type CommentsQuery struct {
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Cursor string `json:"cursor"`
+	after  *Cursor
+}
+
+func (q CommentsQuery) Parse(r *http.Request) (CommentsQuery, error) {
+	qs := r.URL.Query()
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if cursor := qs.Get("cursor"); cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+
+		q.Cursor = cursor
+		q.after = c
+	}
+
+	return q, nil
+}
+
 type CommentsStore struct {
 	db *sql.DB
 }
 
-func (s *CommentsStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+const commentColumns = `
+	c.id, c.post_id, c.parent_id, c.depth, c.user_id, c.content, c.created_at, c.updated_at,
+	c.deleted_at IS NOT NULL, c.hidden_at IS NOT NULL, users.username, users.id
+`
+
+// GetByPostID returns the post's comments that viewerID is allowed to see,
+// most recent first.
+func (s *CommentsStore) GetByPostID(ctx context.Context, postID, viewerID int64) ([]Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c
+		SELECT ` + commentColumns + ` FROM comments c
 		JOIN users on users.id = c.user_id
-		WHERE c.post_id = $1
+		WHERE c.post_id = $1 AND ` + visibleTo("c.user_id", "$2") + `
 		ORDER BY c.created_at DESC;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, postID)
+	rows, err := s.db.QueryContext(ctx, query, postID, viewerID)
 
 	if err != nil {
 		return nil, err
@@ -37,29 +83,69 @@ func (s *CommentsStore) GetByPostID(ctx context.Context, postID int64) ([]Commen
 
 	defer rows.Close()
 
-	comments := []Comment{}
+	return scanComments(rows)
+}
 
-	for rows.Next() {
-		var c Comment
-		c.User = User{}
+// ListByPostID returns a page of the post's comments in chronological order,
+// leaving out the ones viewerID isn't allowed to see. Replies reference their
+// parent through parent_id so clients can rebuild the threads.
+func (s *CommentsStore) ListByPostID(ctx context.Context, postID, viewerID int64, q CommentsQuery) ([]Comment, error) {
+	query := `
+		SELECT ` + commentColumns + ` FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE
+			c.post_id = $1 AND
+			($2::timestamptz IS NULL OR (c.created_at, c.id) > ($2, $3)) AND
+			` + visibleTo("c.user_id", "$5") + `
+		ORDER BY c.created_at, c.id
+		LIMIT $4
+	`
 
-		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.User.Username, &c.User.ID)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
-		if err != nil {
+	createdAt, id := q.after.args()
+
+	rows, err := s.db.QueryContext(ctx, query, postID, createdAt, id, q.Limit, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+func (s *CommentsStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	query := `
+		SELECT ` + commentColumns + ` FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE c.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var c Comment
+
+	err := scanComment(s.db.QueryRowContext(ctx, query, commentID), &c)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
 			return nil, err
 		}
-
-		comments = append(comments, c)
 	}
 
-	return comments, nil
+	return &c, nil
 }
 
 func (s *CommentsStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
+		INSERT INTO comments (post_id, user_id, content, parent_id, depth)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -71,9 +157,12 @@ func (s *CommentsStore) Create(ctx context.Context, comment *Comment) error {
 		comment.PostID,
 		comment.UserID,
 		comment.Content,
+		comment.ParentID,
+		comment.Depth,
 	).Scan(
 		&comment.ID,
 		&comment.CreatedAt,
+		&comment.UpdatedAt,
 	)
 
 	if err != nil {
@@ -82,3 +171,104 @@ func (s *CommentsStore) Create(ctx context.Context, comment *Comment) error {
 
 	return nil
 }
+
+func (s *CommentsStore) Update(ctx context.Context, comment *Comment) error {
+	query := `
+		UPDATE comments SET content = $1, updated_at = NOW()
+		WHERE id = $2 AND deleted_at IS NULL AND hidden_at IS NULL
+		RETURNING updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, comment.Content, comment.ID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete soft deletes a comment. The row stays behind as a placeholder so
+// its replies keep their place in the thread.
+func (s *CommentsStore) Delete(ctx context.Context, commentID int64) error {
+	query := `
+		UPDATE comments SET content = '', deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, commentID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanComment(row rowScanner, c *Comment) error {
+	err := row.Scan(
+		&c.ID,
+		&c.PostID,
+		&c.ParentID,
+		&c.Depth,
+		&c.UserID,
+		&c.Content,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.Deleted,
+		&c.Hidden,
+		&c.User.Username,
+		&c.User.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// deleted and hidden comments are placeholders that don't reveal their
+	// author
+	if c.Deleted || c.Hidden {
+		c.Content = ""
+		c.UserID = 0
+		c.User = User{}
+	}
+
+	return nil
+}
+
+func scanComments(rows *sql.Rows) ([]Comment, error) {
+	comments := []Comment{}
+
+	for rows.Next() {
+		var c Comment
+
+		if err := scanComment(rows, &c); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
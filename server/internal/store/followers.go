@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"database/sql"
+	"net/http"
+	"strconv"
 
 	"github.com/lib/pq"
 )
@@ -13,6 +15,57 @@ type Follower struct {
 	CreatedAt  string `json:"created_at"`
 }
 
+// Relationship describes how the viewing user relates to another user.
+type Relationship struct {
+	Following  bool `json:"following"`
+	FollowedBy bool `json:"followed_by"`
+}
+
+// Connection is a user appearing in a followers, following or friends
+// listing.
+type Connection struct {
+	ID           int64        `json:"id"`
+	Username     string       `json:"username"`
+	FollowedAt   string       `json:"followed_at"`
+	Relationship Relationship `json:"relationship"`
+}
+
+// FollowRequest is a pending request to follow a private account.
+type FollowRequest struct {
+	RequesterID int64  `json:"requester_id"`
+	Username    string `json:"username"`
+	RequestedAt string `json:"requested_at"`
+}
+
+type ConnectionsQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=100"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+func (q ConnectionsQuery) Parse(r *http.Request) (ConnectionsQuery, error) {
+	qs := r.URL.Query()
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
 type FollowerStore struct {
 	db *sql.DB
 }
@@ -27,23 +80,258 @@ func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) er
 
 	_, err := s.db.ExecContext(ctx, query, userID, followerID)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrConflict
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrConflict
+			case "23503":
+				return ErrNotFound
+			}
 		}
+
+		return err
 	}
 
 	return nil
 }
 
+// Unfollow stops following userID, withdrawing the follow request if it
+// hasn't been approved yet.
 func (s *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `DELETE FROM followers WHERE user_id = $1 AND follower_id = $2`
+
+		if _, err := tx.ExecContext(ctx, query, userID, followerID); err != nil {
+			return err
+		}
+
+		query = `DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2`
+
+		_, err := tx.ExecContext(ctx, query, userID, followerID)
+		return err
+	})
+}
+
+// RequestFollow asks to follow a private account. It conflicts when the
+// request is already pending or the requester already follows userID.
+func (s *FollowerStore) RequestFollow(ctx context.Context, requesterID, userID int64) error {
 	query := `
-		DELETE FROM followers
-		WHERE user_id = $1 AND follower_id = $2
+		INSERT INTO follow_requests (user_id, requester_id)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+		ON CONFLICT DO NOTHING
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, followerID)
-	return err
+	res, err := s.db.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// GetFollowRequests lists the requests pending on userID, oldest first.
+func (s *FollowerStore) GetFollowRequests(ctx context.Context, userID int64, q ConnectionsQuery) ([]FollowRequest, error) {
+	query := `
+		SELECT u.id, u.username, r.created_at
+		FROM follow_requests r
+		JOIN users u ON u.id = r.requester_id
+		WHERE r.user_id = $1
+		ORDER BY r.created_at, u.id
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	requests := []FollowRequest{}
+	for rows.Next() {
+		var r FollowRequest
+
+		if err := rows.Scan(&r.RequesterID, &r.Username, &r.RequestedAt); err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, r)
+	}
+
+	return requests, rows.Err()
+}
+
+// ApproveFollowRequest turns a pending request into a follow.
+func (s *FollowerStore) ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := deleteFollowRequest(ctx, tx, userID, requesterID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID, requesterID)
+		return err
+	})
+}
+
+func (s *FollowerStore) RejectFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return deleteFollowRequest(ctx, tx, userID, requesterID)
+	})
+}
+
+func deleteFollowRequest(ctx context.Context, tx *sql.Tx, userID, requesterID int64) error {
+	query := `DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := tx.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetFollowers lists who follows userID, most recent first, along with how
+// each of them relates to viewerID.
+func (s *FollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at, ` + relationshipColumns + `
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC, u.id
+		LIMIT $3 OFFSET $4
+	`
+
+	return s.listConnections(ctx, query, userID, viewerID, q)
+}
+
+// GetFollowing lists whom userID follows, most recent first.
+func (s *FollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at, ` + relationshipColumns + `
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1
+		ORDER BY f.created_at DESC, u.id
+		LIMIT $3 OFFSET $4
+	`
+
+	return s.listConnections(ctx, query, userID, viewerID, q)
+}
+
+// GetFriends lists the users who follow userID back. FollowedAt is when the
+// follow became mutual.
+func (s *FollowerStore) GetFriends(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	query := `
+		SELECT u.id, u.username, GREATEST(f.created_at, b.created_at) AS since, ` + relationshipColumns + `
+		FROM followers f
+		JOIN followers b ON b.user_id = f.follower_id AND b.follower_id = f.user_id
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1
+		ORDER BY since DESC, u.id
+		LIMIT $3 OFFSET $4
+	`
+
+	return s.listConnections(ctx, query, userID, viewerID, q)
+}
+
+func (s *FollowerStore) GetRelationship(ctx context.Context, viewerID, userID int64) (*Relationship, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = $1),
+			EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rel := &Relationship{}
+
+	if err := s.db.QueryRowContext(ctx, query, viewerID, userID).Scan(&rel.Following, &rel.FollowedBy); err != nil {
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+// relationshipColumns selects how the viewer ($2) relates to the listed user
+// u.
+const relationshipColumns = `
+	EXISTS (SELECT 1 FROM followers WHERE user_id = u.id AND follower_id = $2),
+	EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = u.id)
+`
+
+func (s *FollowerStore) listConnections(ctx context.Context, query string, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, viewerID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	connections := []Connection{}
+	for rows.Next() {
+		var c Connection
+
+		err := rows.Scan(
+			&c.ID,
+			&c.Username,
+			&c.FollowedAt,
+			&c.Relationship.Following,
+			&c.Relationship.FollowedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		connections = append(connections, c)
+	}
+
+	return connections, rows.Err()
 }
@@ -8,7 +8,19 @@ import (
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:       &MockPostStore{},
+		Users:       &MockUserStore{},
+		Comments:    &MockCommentStore{},
+		Sessions:    &MockSessionStore{},
+		Followers:   &MockFollowerStore{},
+		Reactions:   &MockReactionStore{},
+		Blocks:      &MockBlockStore{},
+		Search:      &MockSearchStore{},
+		Reports:     &MockReportStore{},
+		Suspensions: &MockSuspensionStore{},
+		Roles:       &MockRoleStore{},
+		Audit:       &MockAuditStore{},
+		Outbox:      &MockOutboxStore{},
 	}
 }
 
@@ -26,7 +38,7 @@ func (m *MockUserStore) GetByEmail(context.Context, string) (*User, error) {
 	return &User{}, nil
 }
 
-func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
+func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration, mail *OutboxMessage) error {
 	return nil
 }
 
@@ -37,3 +49,367 @@ func (m *MockUserStore) Activate(ctx context.Context, t string) error {
 func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+
+func (m *MockUserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration, mail *OutboxMessage) error {
+	return nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token string, user *User) error {
+	return nil
+}
+
+func (m *MockUserStore) ReissueInvitation(ctx context.Context, email, token string, exp time.Duration, mail func(*User) (*OutboxMessage, error)) (*User, error) {
+	return &User{Email: email}, nil
+}
+
+func (m *MockUserStore) DeleteUnactivated(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockUserStore) GetProfileStats(ctx context.Context, userID int64) (*UserStats, error) {
+	return &UserStats{}, nil
+}
+
+func (m *MockUserStore) SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error {
+	return nil
+}
+
+func (m *MockUserStore) UpdateRole(ctx context.Context, userID int64, role *Role, changedBy int64, reason string, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockUserStore) CanViewContent(ctx context.Context, viewerID, authorID int64) (bool, error) {
+	return true, nil
+}
+
+type MockSessionStore struct{}
+
+func (m *MockSessionStore) Create(ctx context.Context, session *Session) error {
+	return nil
+}
+
+func (m *MockSessionStore) Rotate(ctx context.Context, token string, next *Session) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockSessionStore) IsActive(ctx context.Context, familyID string) (bool, error) {
+	return true, nil
+}
+
+type MockPostStore struct{}
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+	return &Post{ID: postID}, nil
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, postID, deletedBy int64, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockPostStore) GetDeleted(ctx context.Context, postID int64, deletedSince time.Time) (*Post, error) {
+	return &Post{ID: postID}, nil
+}
+
+func (m *MockPostStore) Restore(ctx context.Context, postID int64, deletedSince time.Time, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockPostStore) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error) {
+	return []PostRevision{}, nil
+}
+
+func (m *MockPostStore) GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetTagPosts(ctx context.Context, tag string, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockCommentStore struct{}
+
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID, viewerID int64) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) ListByPostID(ctx context.Context, postID, viewerID int64, q CommentsQuery) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	return &Comment{ID: commentID}, nil
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, commentID int64) error {
+	return nil
+}
+
+type MockFollowerStore struct{}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	return []Connection{}, nil
+}
+
+func (m *MockFollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	return []Connection{}, nil
+}
+
+func (m *MockFollowerStore) GetFriends(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error) {
+	return []Connection{}, nil
+}
+
+func (m *MockFollowerStore) GetRelationship(ctx context.Context, viewerID, userID int64) (*Relationship, error) {
+	return &Relationship{}, nil
+}
+
+func (m *MockFollowerStore) RequestFollow(ctx context.Context, requesterID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowRequests(ctx context.Context, userID int64, q ConnectionsQuery) ([]FollowRequest, error) {
+	return []FollowRequest{}, nil
+}
+
+func (m *MockFollowerStore) ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) RejectFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return nil
+}
+
+type MockReactionStore struct{}
+
+func (m *MockReactionStore) Add(ctx context.Context, postID, userID int64, kind string) error {
+	return nil
+}
+
+func (m *MockReactionStore) Remove(ctx context.Context, postID, userID int64, kind string) error {
+	return nil
+}
+
+func (m *MockReactionStore) GetSummary(ctx context.Context, postID, userID int64) (*ReactionSummary, error) {
+	return newReactionSummary(), nil
+}
+
+func (m *MockReactionStore) ListUsers(ctx context.Context, postID, viewerID int64, q ReactionsQuery) ([]Reaction, error) {
+	return []Reaction{}, nil
+}
+
+type MockBlockStore struct{}
+
+func (m *MockBlockStore) Block(ctx context.Context, userID, blockedID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockBlockStore) GetBlocked(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error) {
+	return []BlockedUser{}, nil
+}
+
+func (m *MockBlockStore) Mute(ctx context.Context, userID, mutedID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) Unmute(ctx context.Context, userID, mutedID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) GetMuted(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error) {
+	return []BlockedUser{}, nil
+}
+
+type MockSearchStore struct{}
+
+func (m *MockSearchStore) SearchPosts(ctx context.Context, viewerID int64, q SearchQuery) ([]PostSearchResult, error) {
+	return []PostSearchResult{}, nil
+}
+
+func (m *MockSearchStore) SearchUsers(ctx context.Context, viewerID int64, q SearchQuery) ([]UserSearchResult, error) {
+	return []UserSearchResult{}, nil
+}
+
+func (m *MockSearchStore) SearchTags(ctx context.Context, viewerID int64, q SearchQuery) ([]TagSearchResult, error) {
+	return []TagSearchResult{}, nil
+}
+
+type MockReportStore struct{}
+
+func (m *MockReportStore) Create(ctx context.Context, report *Report) error {
+	return nil
+}
+
+func (m *MockReportStore) GetByID(ctx context.Context, reportID int64) (*Report, error) {
+	return &Report{ID: reportID}, nil
+}
+
+func (m *MockReportStore) List(ctx context.Context, q ReportsQuery) ([]Report, error) {
+	return []Report{}, nil
+}
+
+func (m *MockReportStore) Assign(ctx context.Context, reportID, assigneeID, moderatorID int64, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockReportStore) Resolve(ctx context.Context, reportID int64, res ReportResolution) (*Report, error) {
+	return &Report{ID: reportID, Status: ReportResolved}, nil
+}
+
+func (m *MockReportStore) GetLog(ctx context.Context, q ModerationLogQuery) ([]ModerationLogEntry, error) {
+	return []ModerationLogEntry{}, nil
+}
+
+type MockSuspensionStore struct{}
+
+func (m *MockSuspensionStore) Create(ctx context.Context, suspension *Suspension, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockSuspensionStore) GetActive(ctx context.Context, userID int64) (*Suspension, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockSuspensionStore) GetByUserID(ctx context.Context, userID int64) ([]Suspension, error) {
+	return []Suspension{}, nil
+}
+
+func (m *MockSuspensionStore) Lift(ctx context.Context, userID, liftedBy int64, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockSuspensionStore) LiftExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// MockRoleStore grants the built in roles, user (1), moderator (2) and
+// admin (3), the permissions they're seeded with. TestMockRoleStorePermissions
+// checks them against the seed.
+type MockRoleStore struct{}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, roleName string) (*Role, error) {
+	return &Role{Name: roleName}, nil
+}
+
+func (m *MockRoleStore) GetAll(ctx context.Context) ([]Role, error) {
+	return []Role{}, nil
+}
+
+func (m *MockRoleStore) Create(ctx context.Context, role *Role, audit *AuditEvent) error {
+	return nil
+}
+
+func (m *MockRoleStore) GetChanges(ctx context.Context, userID int64) ([]RoleChange, error) {
+	return []RoleChange{}, nil
+}
+
+func (m *MockRoleStore) GetPermissions(ctx context.Context) (map[int64][]string, error) {
+	return map[int64][]string{
+		2: {PermCommentsModerate, PermPostsUpdateAny, PermReportsReview},
+		3: {
+			PermAuditRead,
+			PermCommentsModerate,
+			PermOutboxManage,
+			PermPostsDeleteAny,
+			PermPostsRestoreAny,
+			PermPostsUpdateAny,
+			PermReportsReview,
+			PermRolesManage,
+			PermUsersSuspend,
+		},
+	}, nil
+}
+
+type MockOutboxStore struct{}
+
+func (m *MockOutboxStore) Enqueue(ctx context.Context, msg *OutboxMessage) error {
+	return nil
+}
+
+func (m *MockOutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	return []OutboxMessage{}, nil
+}
+
+func (m *MockOutboxStore) MarkSent(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockOutboxStore) MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error {
+	return nil
+}
+
+func (m *MockOutboxStore) List(ctx context.Context, q OutboxQuery) ([]OutboxMessage, error) {
+	return []OutboxMessage{}, nil
+}
+
+func (m *MockOutboxStore) Retry(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockOutboxStore) PurgeDeadData(ctx context.Context, deadBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+type MockAuditStore struct{}
+
+func (m *MockAuditStore) Create(ctx context.Context, e *AuditEvent) error {
+	return nil
+}
+
+func (m *MockAuditStore) List(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	return []AuditEvent{}, nil
+}
+
+func (m *MockAuditStore) Export(ctx context.Context, q AuditQuery, fn func(*AuditEvent) error) error {
+	return nil
+}
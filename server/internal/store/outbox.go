@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// OutboxMessage is an email queued for delivery. Data holds its template
+// variables, activation and reset links included, so it's never listed. It's
+// discarded once the message is sent, and kept on dead lettered messages so
+// they can be retried until it's purged.
+type OutboxMessage struct {
+	ID            int64           `json:"id"`
+	Template      string          `json:"template"`
+	Locale        string          `json:"locale"`
+	Username      string          `json:"username"`
+	Email         string          `json:"email"`
+	Data          json.RawMessage `json:"-"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     *string         `json:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	SentAt        *time.Time      `json:"sent_at"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+func NewOutboxMessage(template, locale, username, email string, data any) (*OutboxMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessage{
+		Template: template,
+		Locale:   locale,
+		Username: username,
+		Email:    email,
+		Data:     encoded,
+	}, nil
+}
+
+type OutboxQuery struct {
+	Status string `json:"status" validate:"omitempty,oneof=pending sent dead"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+func (q OutboxQuery) Parse(r *http.Request) (OutboxQuery, error) {
+	qs := r.URL.Query()
+
+	if status := qs.Get("status"); status != "" {
+		q.Status = status
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+type OutboxStore struct {
+	db *sql.DB
+}
+
+const outboxColumns = `id, template, locale, username, email, data, status, attempts, last_error, next_attempt_at, sent_at, created_at`
+
+func (s *OutboxStore) Enqueue(ctx context.Context, msg *OutboxMessage) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return enqueueOutboxMessage(ctx, tx, msg)
+	})
+}
+
+// ClaimDue leases up to limit pending messages that are due for delivery. A
+// leased message isn't handed out again until lease has passed, so a crashed
+// dispatcher doesn't lose it.
+func (s *OutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	query := `
+		UPDATE outbox_messages SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanOutboxMessages(rows)
+}
+
+// MarkSent records a delivery and discards the message data.
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW(), data = NULL
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkFailed records a failed delivery attempt and either schedules the next
+// one or, when dead is set, moves the message to the dead letter state.
+func (s *OutboxStore) MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error {
+	status := OutboxPending
+	if dead {
+		status = OutboxDead
+	}
+
+	query := `
+		UPDATE outbox_messages
+		SET status = $2, attempts = attempts + 1, last_error = $3, next_attempt_at = $4
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id, status, reason, nextAttempt)
+	return err
+}
+
+func (s *OutboxStore) List(ctx context.Context, q OutboxQuery) ([]OutboxMessage, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM outbox_messages
+		WHERE status = $1 OR $1 = ''
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Status, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanOutboxMessages(rows)
+}
+
+// Retry puts a dead lettered message back in the queue with a fresh attempt
+// budget. It returns ErrConflict when the message data was already purged,
+// as the email can't be composed again.
+func (s *OutboxStore) Retry(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead' AND data IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	query = `SELECT EXISTS (SELECT 1 FROM outbox_messages WHERE id = $1 AND status = 'dead')`
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrConflict
+	}
+
+	return ErrNotFound
+}
+
+// PurgeDeadData discards the data of the messages dead lettered before
+// deadBefore, after which they can't be retried. The last failed attempt of
+// a dead lettered message sets its next_attempt_at.
+func (s *OutboxStore) PurgeDeadData(ctx context.Context, deadBefore time.Time) (int64, error) {
+	query := `
+		UPDATE outbox_messages SET data = NULL
+		WHERE status = 'dead' AND data IS NOT NULL AND next_attempt_at < $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, deadBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func enqueueOutboxMessage(ctx context.Context, tx *sql.Tx, msg *OutboxMessage) error {
+	query := `
+		INSERT INTO outbox_messages (template, locale, username, email, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, next_attempt_at, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		msg.Template,
+		msg.Locale,
+		msg.Username,
+		msg.Email,
+		[]byte(msg.Data),
+	).Scan(
+		&msg.ID,
+		&msg.Status,
+		&msg.NextAttemptAt,
+		&msg.CreatedAt,
+	)
+}
+
+func scanOutboxMessages(rows *sql.Rows) ([]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	for rows.Next() {
+		var m OutboxMessage
+
+		err := rows.Scan(
+			&m.ID,
+			&m.Template,
+			&m.Locale,
+			&m.Username,
+			&m.Email,
+			// discarded data is NULL
+			(*[]byte)(&m.Data),
+			&m.Status,
+			&m.Attempts,
+			&m.LastError,
+			&m.NextAttemptAt,
+			&m.SentAt,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxStore(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	outbox := &OutboxStore{db}
+
+	enqueue := func() *OutboxMessage {
+		msg, err := NewOutboxMessage("user_invitation.tmpl", "en", "bob", "bob@example.com", map[string]string{"Username": "bob"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := outbox.Enqueue(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			if _, err := db.Exec(`DELETE FROM outbox_messages WHERE id = $1`, msg.ID); err != nil {
+				t.Error(err)
+			}
+		})
+
+		return msg
+	}
+
+	get := func(id int64) (status string, hasData bool) {
+		query := `SELECT status, data IS NOT NULL FROM outbox_messages WHERE id = $1`
+		if err := db.QueryRowContext(ctx, query, id).Scan(&status, &hasData); err != nil {
+			t.Fatal(err)
+		}
+
+		return status, hasData
+	}
+
+	t.Run("should retry dead lettered messages", func(t *testing.T) {
+		msg := enqueue()
+
+		if err := outbox.MarkFailed(ctx, msg.ID, "connection refused", time.Now(), true); err != nil {
+			t.Fatal(err)
+		}
+
+		if status, hasData := get(msg.ID); status != OutboxDead || !hasData {
+			t.Fatalf("expected a dead lettered message with its data, got %q (data: %v)", status, hasData)
+		}
+
+		if err := outbox.Retry(ctx, msg.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if status, _ := get(msg.ID); status != OutboxPending {
+			t.Errorf("expected the message to be pending again, got %q", status)
+		}
+
+		if err := outbox.Retry(ctx, msg.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected pending messages not to be retried, got %v", err)
+		}
+	})
+
+	t.Run("should discard the data of sent messages", func(t *testing.T) {
+		msg := enqueue()
+
+		if err := outbox.MarkSent(ctx, msg.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if status, hasData := get(msg.ID); status != OutboxSent || hasData {
+			t.Errorf("expected a sent message without data, got %q (data: %v)", status, hasData)
+		}
+	})
+
+	t.Run("should purge the data of long dead messages", func(t *testing.T) {
+		old, recent := enqueue(), enqueue()
+
+		if err := outbox.MarkFailed(ctx, old.ID, "connection refused", time.Now().Add(-48*time.Hour), true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := outbox.MarkFailed(ctx, recent.ID, "connection refused", time.Now(), true); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := outbox.PurgeDeadData(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, hasData := get(old.ID); hasData {
+			t.Error("expected the data of the old message to be purged")
+		}
+
+		if _, hasData := get(recent.ID); !hasData {
+			t.Error("expected the data of the recent message to be kept")
+		}
+
+		if err := outbox.Retry(ctx, old.ID); !errors.Is(err, ErrConflict) {
+			t.Errorf("expected purged messages not to be retried, got %v", err)
+		}
+
+		if err := outbox.Retry(ctx, recent.ID); err != nil {
+			t.Errorf("expected the recent message to be retried, got %v", err)
+		}
+	})
+}
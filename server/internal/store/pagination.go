@@ -1,6 +1,9 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,6 +18,10 @@ type PaginatedFeedQuery struct {
 	Search string   `json:"search" validate:"max=100"`
 	Since  string   `json:"since"`
 	Until  string   `json:"until"`
+	Cursor string   `json:"cursor"`
+	after  *Cursor
+	since  *time.Time
+	until  *time.Time
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -24,7 +31,7 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 	if limit != "" {
 		l, err := strconv.Atoi(limit)
 		if err != nil {
-			return fq, nil
+			return fq, err
 		}
 
 		fq.Limit = l
@@ -34,12 +41,27 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 	if offset != "" {
 		l, err := strconv.Atoi(offset)
 		if err != nil {
-			return fq, nil
+			return fq, err
 		}
 
 		fq.Offset = l
 	}
 
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		if fq.Offset != 0 {
+			return fq, errors.New("cursor and offset can't be combined")
+		}
+
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return fq, err
+		}
+
+		fq.Cursor = cursor
+		fq.after = c
+	}
+
 	sort := qs.Get("sort")
 	if sort != "" {
 		fq.Sort = sort
@@ -47,7 +69,12 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	tags := qs.Get("tags")
 	if tags != "" {
-		fq.Tags = strings.Split(tags, ",")
+		t, err := NormalizeTags(strings.Split(tags, ","))
+		if err != nil {
+			return fq, err
+		}
+
+		fq.Tags = t
 	}
 
 	search := qs.Get("search")
@@ -57,23 +84,90 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := qs.Get("since")
 	if since != "" {
-		fq.Since = parseTime(since)
+		t, err := parseTime(since)
+		if err != nil {
+			return fq, fmt.Errorf("invalid since: %w", err)
+		}
+
+		fq.Since = t.Format(time.RFC3339)
+		fq.since = &t
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		fq.Until = parseTime(until)
+		t, err := parseTime(until)
+		if err != nil {
+			return fq, fmt.Errorf("invalid until: %w", err)
+		}
+
+		fq.Until = t.Format(time.RFC3339)
+		fq.until = &t
+	}
+
+	if fq.since != nil && fq.until != nil && fq.since.After(*fq.until) {
+		return fq, errors.New("since must not be after until")
 	}
 
 	return fq, nil
 }
 
-func parseTime(s string) string {
-	t, err := time.Parse(time.DateTime, s)
+var errInvalidTime = errors.New("expected an RFC 3339 or \"2006-01-02 15:04:05\" timestamp")
+
+// parseTime accepts RFC 3339 timestamps as well as time.DateTime, which is
+// read as UTC.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateTime} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errInvalidTime
+}
+
+// Cursor marks the last row of a keyset paginated page. It's handed to
+// clients as an opaque token.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func EncodeCursor(createdAt string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt + "|" + strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
 
+	i, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
-		return ""
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: t, ID: i}, nil
+}
+
+// args returns the cursor as query arguments, both nil when there is no
+// cursor so the query starts from the first page.
+func (c *Cursor) args() (any, any) {
+	if c == nil {
+		return nil, nil
 	}
 
-	return t.Format(time.DateTime)
+	return c.CreatedAt, c.ID
 }
@@ -4,21 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID        int64            `json:"id"`
+	Content   string           `json:"content"`
+	Title     string           `json:"title"`
+	UserID    int64            `json:"user_id"`
+	Tags      []string         `json:"tags"`
+	CreatedAt string           `json:"created_at"`
+	UpdatedAt string           `json:"updated_at"`
+	Version   int              `json:"version"`
+	Comments  []Comment        `json:"comments"`
+	User      User             `json:"user"`
+	Reactions *ReactionSummary `json:"reactions,omitempty"`
+	// Edited is set once the post has been updated after its creation
+	Edited bool `json:"edited"`
+	// DeletedAt and DeletedBy are only set on posts fetched with GetDeleted
+	DeletedAt *string `json:"deleted_at,omitempty"`
+	DeletedBy *int64  `json:"deleted_by,omitempty"`
 }
 
 type PostWithMetadata struct {
@@ -30,40 +37,115 @@ type PostsStore struct {
 	db *sql.DB
 }
 
+// Create saves the post along with its first revision.
 func (s *PostsStore) Create(ctx context.Context, post *Post) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO posts (content, title, user_id, tags)
+			VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at, version
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Version,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		return createRevision(ctx, tx, post, post.UserID)
+	})
+}
+
+func (s *PostsStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 	query := `
-		INSERT INTO posts (content, title, user_id, tags)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		SELECT id, user_id, title, content, created_at, updated_at, tags, version
+		FROM posts p
+		WHERE id = $1 AND ` + published("p") + `
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
+	var post Post
+
+	err := s.db.QueryRowContext(ctx, query, postID).Scan(
 		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
 		&post.CreatedAt,
 		&post.UpdatedAt,
+		pq.Array(&post.Tags),
+		&post.Version,
 	)
 
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
 	}
 
-	return nil
+	post.Edited = post.Version > 0
+
+	return &post, nil
 }
 
-func (s *PostsStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+// Delete soft deletes the post on behalf of deletedBy, recording audit
+// along with it. It can be restored until PurgeDeleted removes it for good.
+func (s *PostsStore) Delete(ctx context.Context, postID, deletedBy int64, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE posts SET deleted_at = NOW(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, postID, deletedBy)
+
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// GetDeleted fetches a post that was deleted after deletedSince, returning
+// ErrNotFound for posts that aren't deleted or were deleted before then.
+func (s *PostsStore) GetDeleted(ctx context.Context, postID int64, deletedSince time.Time) (*Post, error) {
 	query := `
-		SELECT id, user_id, title, content, created_at, updated_at, tags, version
+		SELECT id, user_id, title, content, created_at, updated_at, tags, version, deleted_at, deleted_by
 		FROM posts
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at >= $2
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -71,7 +153,7 @@ func (s *PostsStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 
 	var post Post
 
-	err := s.db.QueryRowContext(ctx, query, postID).Scan(
+	err := s.db.QueryRowContext(ctx, query, postID, deletedSince).Scan(
 		&post.ID,
 		&post.UserID,
 		&post.Title,
@@ -80,6 +162,8 @@ func (s *PostsStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		&post.UpdatedAt,
 		pq.Array(&post.Tags),
 		&post.Version,
+		&post.DeletedAt,
+		&post.DeletedBy,
 	)
 
 	if err != nil {
@@ -91,67 +175,151 @@ func (s *PostsStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		}
 	}
 
+	post.Edited = post.Version > 0
+
 	return &post, nil
 }
 
-func (s *PostsStore) Delete(ctx context.Context, postID int64) error {
-	query := `DELETE FROM posts WHERE id = $1`
+// Restore undeletes a post deleted after deletedSince, recording audit along
+// with it.
+func (s *PostsStore) Restore(ctx context.Context, postID int64, deletedSince time.Time, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE posts SET deleted_at = NULL, deleted_by = NULL
+			WHERE id = $1 AND deleted_at >= $2
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, postID, deletedSince)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// PurgeDeleted permanently removes the posts deleted before deletedBefore.
+// Their comments, reactions and revisions go along with them.
+func (s *PostsStore) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `DELETE FROM posts WHERE deleted_at < $1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := s.db.ExecContext(ctx, query, postID)
-
+	res, err := s.db.ExecContext(ctx, query, deletedBefore)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	rows, err := res.RowsAffected()
+	return res.RowsAffected()
+}
+
+// Update saves the post if it's still at post.Version, returning
+// ErrEditConflict when someone else updated it first and ErrNotFound when
+// it was deleted in the meantime. The new version is recorded as a revision
+// made by editorID.
+func (s *PostsStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE posts p
+			SET title = $1, content = $2, tags = $3, version = version + 1, updated_at = NOW()
+			WHERE id = $4 AND version = $5 AND ` + published("p") + `
+			RETURNING version, updated_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Title,
+			post.Content,
+			pq.Array(post.Tags),
+			post.ID,
+			post.Version,
+		).Scan(&post.Version, &post.UpdatedAt)
 
-	if err != nil {
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return postUpdateError(ctx, tx, post.ID)
+			default:
+				return err
+			}
+		}
+
+		post.Edited = true
+
+		return createRevision(ctx, tx, post, editorID)
+	})
+}
+
+// postUpdateError tells apart why an update matched no rows: the post is
+// either gone or at another version than the one the edit was based on.
+func postUpdateError(ctx context.Context, tx *sql.Tx, postID int64) error {
+	query := `SELECT EXISTS (SELECT 1 FROM posts p WHERE id = $1 AND ` + published("p") + `)`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, query, postID).Scan(&exists); err != nil {
 		return err
 	}
 
-	if rows == 0 {
+	if !exists {
 		return ErrNotFound
 	}
 
-	return nil
+	return ErrEditConflict
 }
 
-func (s *PostsStore) Update(ctx context.Context, post *Post) error {
-	query := `
-		UPDATE posts 
-		SET title = $1, content = $2, version = version + 1
-		WHERE id = $3 AND version = $4
-		RETURNING version
+// GetUserFeed returns the user's own posts along with the posts of the users
+// they follow and haven't muted. Pages are either addressed by offset or,
+// when fq.Cursor is set, by the (created_at, id) keyset of the last post of
+// the previous page.
+func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	scope := `
+		p.user_id = $1 OR (
+			p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1) AND
+			p.user_id NOT IN (SELECT muted_id FROM user_mutes WHERE user_id = $1)
+		)
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
+	return s.listPosts(ctx, scope, userID, userID, fq)
+}
 
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrNotFound
-		default:
-			return err
-		}
-	}
+// GetUserPosts returns the posts written by authorID, filtered and paginated
+// like the feed. viewerID is who the reactions are summarized for.
+func (s *PostsStore) GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return s.listPosts(ctx, `p.user_id = $1`, authorID, viewerID, fq)
+}
 
-	return nil
+// GetTagPosts returns the posts tagged with tag that viewerID can see.
+func (s *PostsStore) GetTagPosts(ctx context.Context, tag string, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return s.listPosts(ctx, `$1::varchar = ANY(p.tags)`, tag, viewerID, fq)
 }
 
-func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+// listPosts runs the feed query over the posts matched by scope, a predicate
+// that may reference scopeArg as $1. Posts viewerID isn't allowed to see are
+// left out.
+func (s *PostsStore) listPosts(ctx context.Context, scope string, scopeArg any, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	// sort is validated to be either asc or desc
+	direction, comparison := "DESC", "<"
+	if fq.Sort == "asc" {
+		direction, comparison = "ASC", ">"
+	}
+
 	query := `
 		SELECT
 			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, u.username,
@@ -159,27 +327,38 @@ func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 			FROM posts p
 		LEFT JOIN comments c ON c.post_id = p.id
 		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
 		WHERE
-			f.user_id = $1 AND
+			(` + scope + `) AND
+			` + published("p") + ` AND
 			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
+			(p.tags @> $5 OR $5 = '{}') AND
+			($6::timestamptz IS NULL OR (p.created_at, p.id) ` + comparison + ` ($6, $7)) AND
+			($8::timestamptz IS NULL OR p.created_at >= $8) AND
+			($9::timestamptz IS NULL OR p.created_at <= $9) AND
+			` + visibleTo("p.user_id", "$10") + `
 		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
+		ORDER BY p.created_at ` + direction + `, p.id ` + direction + `
 		LIMIT $2 OFFSET $3
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
+	createdAt, id := fq.after.args()
+
 	rows, err := s.db.QueryContext(
 		ctx,
 		query,
-		userID,
+		scopeArg,
 		fq.Limit,
 		fq.Offset,
 		fq.Search,
 		pq.Array(fq.Tags),
+		createdAt,
+		id,
+		fq.since,
+		fq.until,
+		viewerID,
 	)
 
 	if err != nil {
@@ -188,7 +367,7 @@ func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 
 	defer rows.Close()
 
-	var feeds []PostWithMetadata
+	posts := []PostWithMetadata{}
 	for rows.Next() {
 		var post PostWithMetadata
 		err := rows.Scan(
@@ -207,8 +386,27 @@ func (s *PostsStore) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 			return nil, err
 		}
 
-		feeds = append(feeds, post)
+		post.Edited = post.Version > 0
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	postIDs := make([]int64, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	reactions, err := getReactionSummaries(ctx, s.db, postIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posts {
+		posts[i].Reactions = reactions[posts[i].ID]
 	}
 
-	return feeds, nil
+	return posts, nil
 }
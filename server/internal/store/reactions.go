@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// ReactionKinds is the fixed set of reactions a post can receive, in the
+// format expected by the validator's oneof rule.
+const ReactionKinds = "like love laugh wow sad"
+
+type Reaction struct {
+	PostID    int64  `json:"post_id"`
+	Kind      string `json:"kind"`
+	CreatedAt string `json:"created_at"`
+	User      User   `json:"user"`
+}
+
+// ReactionSummary aggregates the reactions on a post. ReactedByMe lists the
+// kinds the requesting user has reacted with.
+type ReactionSummary struct {
+	Counts      map[string]int `json:"counts"`
+	Total       int            `json:"total"`
+	ReactedByMe []string       `json:"reacted_by_me"`
+}
+
+func newReactionSummary() *ReactionSummary {
+	return &ReactionSummary{
+		Counts:      map[string]int{},
+		ReactedByMe: []string{},
+	}
+}
+
+type ReactionsQuery struct {
+	// Kind is checked against ReactionKinds by the handler
+	Kind   string `json:"kind"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+func (q ReactionsQuery) Parse(r *http.Request) (ReactionsQuery, error) {
+	qs := r.URL.Query()
+
+	if kind := qs.Get("kind"); kind != "" {
+		q.Kind = kind
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+type ReactionsStore struct {
+	db *sql.DB
+}
+
+// Add reacts to a post. Reacting twice with the same kind is a no-op.
+func (s *ReactionsStore) Add(ctx context.Context, postID, userID int64, kind string) error {
+	query := `
+		INSERT INTO post_reactions (post_id, user_id, kind) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, postID, userID, kind)
+	return err
+}
+
+func (s *ReactionsStore) Remove(ctx context.Context, postID, userID int64, kind string) error {
+	query := `
+		DELETE FROM post_reactions
+		WHERE post_id = $1 AND user_id = $2 AND kind = $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, postID, userID, kind)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ReactionsStore) GetSummary(ctx context.Context, postID, userID int64) (*ReactionSummary, error) {
+	summaries, err := getReactionSummaries(ctx, s.db, []int64{postID}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return summaries[postID], nil
+}
+
+// ListUsers returns who reacted to a post, most recent first.
+func (s *ReactionsStore) ListUsers(ctx context.Context, postID, viewerID int64, q ReactionsQuery) ([]Reaction, error) {
+	query := `
+		SELECT r.post_id, r.kind, r.created_at, u.id, u.username
+		FROM post_reactions r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.post_id = $1 AND (r.kind = $2 OR $2 = '')
+			AND ` + visibleTo("u.id", "$5") + `
+		ORDER BY r.created_at DESC, u.id
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID, q.Kind, q.Limit, q.Offset, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reactions := []Reaction{}
+	for rows.Next() {
+		var r Reaction
+
+		err := rows.Scan(
+			&r.PostID,
+			&r.Kind,
+			&r.CreatedAt,
+			&r.User.ID,
+			&r.User.Username,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		reactions = append(reactions, r)
+	}
+
+	return reactions, rows.Err()
+}
+
+// getReactionSummaries aggregates the reactions of several posts in a single
+// query. Every requested post gets a summary, even without reactions.
+func getReactionSummaries(ctx context.Context, db *sql.DB, postIDs []int64, userID int64) (map[int64]*ReactionSummary, error) {
+	query := `
+		SELECT post_id, kind, COUNT(*), BOOL_OR(user_id = $2)
+		FROM post_reactions
+		WHERE post_id = ANY($1)
+		GROUP BY post_id, kind
+		ORDER BY post_id, kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	summaries := make(map[int64]*ReactionSummary, len(postIDs))
+	for _, id := range postIDs {
+		summaries[id] = newReactionSummary()
+	}
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(postIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postID int64
+			kind   string
+			count  int
+			mine   bool
+		)
+
+		if err := rows.Scan(&postID, &kind, &count, &mine); err != nil {
+			return nil, err
+		}
+
+		summary := summaries[postID]
+		summary.Counts[kind] = count
+		summary.Total += count
+
+		if mine {
+			summary.ReactedByMe = append(summary.ReactedByMe, kind)
+		}
+	}
+
+	return summaries, rows.Err()
+}
@@ -0,0 +1,527 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ReportReasons lists the reason codes a report can be filed under.
+const ReportReasons = "spam harassment hate violence sexual misinformation other"
+
+const (
+	ReportOpen     = "open"
+	ReportResolved = "resolved"
+)
+
+// Moderation actions resolving a report. Whatever the action, every open
+// report on the same post or comment is resolved along with it.
+const (
+	ActionDismiss = "dismiss"
+	ActionHide    = "hide"
+	ActionWarn    = "warn"
+	ActionSuspend = "suspend"
+)
+
+// Moderation actions that aren't report resolutions. Assignments hand a
+// report over to a moderator, bans and lifts are made by admins.
+const (
+	ActionAssign = "assign"
+	ActionBan    = "ban"
+	ActionLift   = "lift"
+)
+
+// Report flags a post, or one of its comments when CommentID is set, for the
+// moderators to review. AuthorID, Title and Content describe the reported
+// content.
+type Report struct {
+	ID         int64   `json:"id"`
+	ReporterID int64   `json:"reporter_id"`
+	PostID     int64   `json:"post_id"`
+	CommentID  *int64  `json:"comment_id"`
+	Reason     string  `json:"reason"`
+	Details    string  `json:"details"`
+	Status     string  `json:"status"`
+	AssigneeID *int64  `json:"assignee_id"`
+	Resolution *string `json:"resolution"`
+	ResolvedBy *int64  `json:"resolved_by"`
+	ResolvedAt *string `json:"resolved_at"`
+	CreatedAt  string  `json:"created_at"`
+	AuthorID   int64   `json:"author_id"`
+	Title      string  `json:"title,omitempty"`
+	Content    string  `json:"content"`
+}
+
+type ReportsQuery struct {
+	Status string `json:"status" validate:"oneof=open resolved"`
+	Reason string `json:"reason" validate:"omitempty,oneof=spam harassment hate violence sexual misinformation other"`
+	Type   string `json:"type" validate:"omitempty,oneof=post comment"`
+	// Assignee is either a user ID or "unassigned"
+	Assignee string `json:"assignee"`
+	Limit    int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset   int    `json:"offset" validate:"gte=0"`
+}
+
+func (q ReportsQuery) Parse(r *http.Request) (ReportsQuery, error) {
+	qs := r.URL.Query()
+
+	if status := qs.Get("status"); status != "" {
+		q.Status = status
+	}
+
+	q.Reason = qs.Get("reason")
+	q.Type = qs.Get("type")
+	q.Assignee = qs.Get("assignee")
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+// ReportResolution is what a moderator decided about a report.
+type ReportResolution struct {
+	Action      string
+	Note        string
+	ModeratorID int64
+	// SuspendUntil is when the suspension of a suspend action ends. It
+	// never ends when nil.
+	SuspendUntil *time.Time
+	// Warning composes the email a warn action sends to the author of the
+	// reported content. It's required for warn actions.
+	Warning func(author *User, report *Report) (*OutboxMessage, error)
+	// Audit describes the resolution of report for the audit log, which
+	// records it along with the resolution.
+	Audit func(report *Report) *AuditEvent
+}
+
+type ModerationLogEntry struct {
+	ID          int64  `json:"id"`
+	ModeratorID int64  `json:"moderator_id"`
+	Action      string `json:"action"`
+	ReportID    *int64 `json:"report_id"`
+	PostID      *int64 `json:"post_id"`
+	CommentID   *int64 `json:"comment_id"`
+	SubjectID   *int64 `json:"subject_id"`
+	Note        string `json:"note"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type ModerationLogQuery struct {
+	SubjectID int64 `json:"subject_id" validate:"gte=0"`
+	Limit     int   `json:"limit" validate:"gte=1,lte=100"`
+	Offset    int   `json:"offset" validate:"gte=0"`
+}
+
+func (q ModerationLogQuery) Parse(r *http.Request) (ModerationLogQuery, error) {
+	qs := r.URL.Query()
+
+	if subject := qs.Get("subject_id"); subject != "" {
+		id, err := strconv.ParseInt(subject, 10, 64)
+		if err != nil {
+			return q, err
+		}
+
+		q.SubjectID = id
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+type ReportsStore struct {
+	db *sql.DB
+}
+
+const reportColumns = `
+	r.id, r.reporter_id, r.post_id, r.comment_id, r.reason, r.details, r.status, r.assignee_id,
+	r.resolution, r.resolved_by, r.resolved_at, r.created_at,
+	COALESCE(c.user_id, p.user_id), CASE WHEN c.id IS NULL THEN p.title ELSE '' END,
+	COALESCE(c.content, p.content)
+`
+
+const reportTables = `
+	reports r
+	JOIN posts p ON p.id = r.post_id
+	LEFT JOIN comments c ON c.id = r.comment_id
+`
+
+// Create files a report, returning ErrConflict when the reporter already has
+// an open report on the same content.
+func (s *ReportsStore) Create(ctx context.Context, report *Report) error {
+	query := `
+		INSERT INTO reports (reporter_id, post_id, comment_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		report.ReporterID,
+		report.PostID,
+		report.CommentID,
+		report.Reason,
+		report.Details,
+	).Scan(
+		&report.ID,
+		&report.Status,
+		&report.CreatedAt,
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *ReportsStore) GetByID(ctx context.Context, reportID int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM ` + reportTables + ` WHERE r.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var report Report
+
+	if err := scanReport(s.db.QueryRowContext(ctx, query, reportID), &report); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &report, nil
+}
+
+// List returns the moderation queue, oldest reports first.
+func (s *ReportsStore) List(ctx context.Context, q ReportsQuery) ([]Report, error) {
+	query := `
+		SELECT ` + reportColumns + ` FROM ` + reportTables + `
+		WHERE
+			r.status = $1 AND
+			($2 = '' OR r.reason = $2) AND
+			($3 = '' OR ($3 = 'post') = (r.comment_id IS NULL)) AND
+			($4 = '' OR ($4 = 'unassigned' AND r.assignee_id IS NULL) OR r.assignee_id::text = $4)
+		ORDER BY r.created_at, r.id
+		LIMIT $5 OFFSET $6
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Status, q.Reason, q.Type, q.Assignee, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+
+		if err := scanReport(rows, &report); err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// Assign hands an open report over to assigneeID on behalf of moderatorID,
+// recording audit along with it.
+func (s *ReportsStore) Assign(ctx context.Context, reportID, assigneeID, moderatorID int64, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE reports SET assignee_id = $2
+			WHERE id = $1 AND status = 'open'
+			RETURNING post_id, comment_id
+		`
+
+		entry := &ModerationLogEntry{
+			ModeratorID: moderatorID,
+			Action:      ActionAssign,
+			ReportID:    &reportID,
+			SubjectID:   &assigneeID,
+		}
+
+		err := tx.QueryRowContext(ctx, query, reportID, assigneeID).Scan(&entry.PostID, &entry.CommentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+					return ErrNotFound
+				}
+
+				return err
+			}
+		}
+
+		if err := appendModerationLog(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// Resolve applies a moderator's decision to the reported content and
+// resolves every open report on it. It returns ErrNotFound if the report
+// doesn't exist and ErrConflict if it was already resolved.
+func (s *ReportsStore) Resolve(ctx context.Context, reportID int64, res ReportResolution) (*Report, error) {
+	var report Report
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `SELECT ` + reportColumns + ` FROM ` + reportTables + ` WHERE r.id = $1 FOR UPDATE OF r`
+
+		if err := scanReport(tx.QueryRowContext(ctx, query, reportID), &report); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if report.Status != ReportOpen {
+			return ErrConflict
+		}
+
+		switch res.Action {
+		case ActionHide:
+			query = `UPDATE posts SET hidden_at = NOW() WHERE id = $1`
+			target := report.PostID
+
+			if report.CommentID != nil {
+				query = `UPDATE comments SET hidden_at = NOW() WHERE id = $1`
+				target = *report.CommentID
+			}
+
+			if _, err := tx.ExecContext(ctx, query, target); err != nil {
+				return err
+			}
+		case ActionSuspend:
+			suspension := &Suspension{
+				UserID:      report.AuthorID,
+				Reason:      res.Note,
+				SuspendedBy: &res.ModeratorID,
+				ExpiresAt:   res.SuspendUntil,
+			}
+
+			if err := createSuspension(ctx, tx, suspension); err != nil {
+				return err
+			}
+		case ActionWarn:
+			if err := enqueueWarning(ctx, tx, &report, res.Warning); err != nil {
+				return err
+			}
+		}
+
+		query = `
+			UPDATE reports
+			SET status = 'resolved', resolution = $3, resolved_by = $4, resolved_at = NOW()
+			WHERE status = 'open' AND post_id = $1 AND comment_id IS NOT DISTINCT FROM $2
+		`
+
+		_, err := tx.ExecContext(ctx, query, report.PostID, report.CommentID, res.Action, res.ModeratorID)
+		if err != nil {
+			return err
+		}
+
+		entry := &ModerationLogEntry{
+			ModeratorID: res.ModeratorID,
+			Action:      res.Action,
+			ReportID:    &report.ID,
+			PostID:      &report.PostID,
+			CommentID:   report.CommentID,
+			SubjectID:   &report.AuthorID,
+			Note:        res.Note,
+		}
+
+		if err := appendModerationLog(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		if res.Audit == nil {
+			return errors.New("resolutions need an audit event")
+		}
+
+		return createAuditEvent(ctx, tx, res.Audit(&report))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Status = ReportResolved
+	report.Resolution = &res.Action
+	report.ResolvedBy = &res.ModeratorID
+
+	return &report, nil
+}
+
+// enqueueWarning queues the warning email composed by mail for the author of
+// the reported content, so that it's only sent if the resolution commits.
+func enqueueWarning(ctx context.Context, tx *sql.Tx, report *Report, mail func(*User, *Report) (*OutboxMessage, error)) error {
+	if mail == nil {
+		return errors.New("warn resolutions need a warning email")
+	}
+
+	query := `SELECT id, username, email, language FROM users WHERE id = $1`
+
+	var author User
+	err := tx.QueryRowContext(ctx, query, report.AuthorID).Scan(&author.ID, &author.Username, &author.Email, &author.Language)
+	if err != nil {
+		return err
+	}
+
+	msg, err := mail(&author, report)
+	if err != nil {
+		return err
+	}
+
+	return enqueueOutboxMessage(ctx, tx, msg)
+}
+
+// GetLog lists the moderation log, most recent first, optionally narrowed
+// down to the entries about one user.
+func (s *ReportsStore) GetLog(ctx context.Context, q ModerationLogQuery) ([]ModerationLogEntry, error) {
+	query := `
+		SELECT id, moderator_id, action, report_id, post_id, comment_id, subject_id, note, created_at
+		FROM moderation_log
+		WHERE $1 = 0 OR subject_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.SubjectID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []ModerationLogEntry{}
+	for rows.Next() {
+		var e ModerationLogEntry
+
+		err := rows.Scan(
+			&e.ID,
+			&e.ModeratorID,
+			&e.Action,
+			&e.ReportID,
+			&e.PostID,
+			&e.CommentID,
+			&e.SubjectID,
+			&e.Note,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// appendModerationLog records a moderation action. The log is append only,
+// the database refuses updates and deletes.
+func appendModerationLog(ctx context.Context, tx *sql.Tx, e *ModerationLogEntry) error {
+	query := `
+		INSERT INTO moderation_log (moderator_id, action, report_id, post_id, comment_id, subject_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		e.ModeratorID,
+		e.Action,
+		e.ReportID,
+		e.PostID,
+		e.CommentID,
+		e.SubjectID,
+		e.Note,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+func scanReport(row rowScanner, r *Report) error {
+	return row.Scan(
+		&r.ID,
+		&r.ReporterID,
+		&r.PostID,
+		&r.CommentID,
+		&r.Reason,
+		&r.Details,
+		&r.Status,
+		&r.AssigneeID,
+		&r.Resolution,
+		&r.ResolvedBy,
+		&r.ResolvedAt,
+		&r.CreatedAt,
+		&r.AuthorID,
+		&r.Title,
+		&r.Content,
+	)
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is a post as it was at a given version. Editor is empty if
+// the editing account no longer exists, or isn't known for the versions
+// posts were at when revisions started being recorded.
+type PostRevision struct {
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id"`
+	Version   int      `json:"version"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	EditorID  *int64   `json:"editor_id"`
+	Editor    *string  `json:"editor"`
+	CreatedAt string   `json:"created_at"`
+}
+
+const revisionColumns = `r.id, r.post_id, r.version, r.title, r.content, r.tags, r.editor_id, u.username, r.created_at`
+
+// GetRevisions lists the post's revisions, the most recent first.
+func (s *PostsStore) GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error) {
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM post_revisions r
+		JOIN posts p ON p.id = r.post_id AND ` + published("p") + `
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.post_id = $1
+		ORDER BY r.version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+
+		if err := scanRevision(rows, &r); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision returns the post as it was at version, or ErrNotFound when the
+// post has no such version or has been deleted or hidden.
+func (s *PostsStore) GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM post_revisions r
+		JOIN posts p ON p.id = r.post_id AND ` + published("p") + `
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.post_id = $1 AND r.version = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var r PostRevision
+
+	if err := scanRevision(s.db.QueryRowContext(ctx, query, postID, version), &r); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+func createRevision(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
+	query := `
+		INSERT INTO post_revisions (post_id, version, title, content, tags, editor_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		post.ID,
+		post.Version,
+		post.Title,
+		post.Content,
+		pq.Array(post.Tags),
+		editorID,
+	)
+	return err
+}
+
+func scanRevision(row rowScanner, r *PostRevision) error {
+	return row.Scan(
+		&r.ID,
+		&r.PostID,
+		&r.Version,
+		&r.Title,
+		&r.Content,
+		pq.Array(&r.Tags),
+		&r.EditorID,
+		&r.Editor,
+		&r.CreatedAt,
+	)
+}
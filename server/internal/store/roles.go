@@ -3,13 +3,45 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Permissions that can be granted to roles, as seeded in the permissions
+// table. Users can always act on their own content without them.
+const (
+	PermPostsUpdateAny   = "posts:update:any"
+	PermPostsDeleteAny   = "posts:delete:any"
+	PermPostsRestoreAny  = "posts:restore:any"
+	PermCommentsModerate = "comments:moderate"
+	PermReportsReview    = "reports:review"
+	PermUsersSuspend     = "users:suspend"
+	PermRolesManage      = "roles:manage"
+	PermOutboxManage     = "outbox:manage"
+	PermAuditRead        = "audit:read"
 )
 
+// Role groups users by what they're allowed to do. Level only ranks roles so
+// that users can't act on their peers or superiors; what a role may do is
+// given by its permissions.
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int64  `json:"level"`
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Level       int64    `json:"level"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// RoleChange records a user being moved from one role to another.
+type RoleChange struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	OldRole   string `json:"old_role"`
+	NewRole   string `json:"new_role"`
+	ChangedBy *int64 `json:"changed_by"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
 }
 
 type RolesStore struct {
@@ -18,7 +50,7 @@ type RolesStore struct {
 
 func (s *RolesStore) GetByName(ctx context.Context, roleName string) (*Role, error) {
 	query := `
-		SELECT id, name, description, level
+		SELECT id, name, COALESCE(description, ''), level
 		FROM roles
 		WHERE name = $1
 	`
@@ -33,8 +65,176 @@ func (s *RolesStore) GetByName(ctx context.Context, roleName string) (*Role, err
 	)
 
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
 	}
 
 	return &role, nil
 }
+
+// GetAll lists the roles along with their permissions, from the least to
+// the most privileged.
+func (s *RolesStore) GetAll(ctx context.Context) ([]Role, error) {
+	query := `
+		SELECT
+			r.id, r.name, COALESCE(r.description, ''), r.level,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		GROUP BY r.id
+		ORDER BY r.level, r.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Level,
+			pq.Array(&role.Permissions),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Create adds a role granted role.Permissions, recording audit about the new
+// role along with it. It returns ErrConflict if the name is taken and
+// ErrNotFound if one of the permissions doesn't exist.
+func (s *RolesStore) Create(ctx context.Context, role *Role, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO roles (name, description, level)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`
+
+		err := tx.QueryRowContext(ctx, query, role.Name, role.Description, role.Level).Scan(&role.ID)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		query = `
+			INSERT INTO role_permissions (role_id, permission)
+			SELECT $1, unnest($2::varchar[])
+			ON CONFLICT DO NOTHING
+		`
+
+		if _, err := tx.ExecContext(ctx, query, role.ID, pq.Array(role.Permissions)); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+
+			return err
+		}
+
+		audit.TargetID = &role.ID
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// GetPermissions returns the permissions granted to each role, keyed by role
+// ID. Roles without permissions are left out.
+func (s *RolesStore) GetPermissions(ctx context.Context) (map[int64][]string, error) {
+	query := `SELECT role_id, permission FROM role_permissions ORDER BY role_id, permission`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	permissions := make(map[int64][]string)
+	for rows.Next() {
+		var (
+			roleID     int64
+			permission string
+		)
+
+		if err := rows.Scan(&roleID, &permission); err != nil {
+			return nil, err
+		}
+
+		permissions[roleID] = append(permissions[roleID], permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// GetChanges lists the role changes of a user, most recent first.
+func (s *RolesStore) GetChanges(ctx context.Context, userID int64) ([]RoleChange, error) {
+	query := `
+		SELECT rc.id, rc.user_id, o.name, n.name, rc.changed_by, rc.reason, rc.created_at
+		FROM role_changes rc
+		JOIN roles o ON o.id = rc.old_role_id
+		JOIN roles n ON n.id = rc.new_role_id
+		WHERE rc.user_id = $1
+		ORDER BY rc.created_at DESC, rc.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	changes := []RoleChange{}
+	for rows.Next() {
+		var c RoleChange
+
+		err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.OldRole,
+			&c.NewRole,
+			&c.ChangedBy,
+			&c.Reason,
+			&c.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestMockRoleStorePermissions keeps the permissions MockRoleStore grants the
+// built in roles in step with the ones they're seeded with.
+func TestMockRoleStorePermissions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	roles := &RolesStore{db}
+
+	seeded, err := roles.GetPermissions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mocked, err := (&MockRoleStore{}).GetPermissions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"user", "moderator", "admin"} {
+		role, err := roles.GetByName(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := append([]string{}, seeded[role.ID]...)
+		got := append([]string{}, mocked[role.ID]...)
+		sort.Strings(want)
+		sort.Strings(got)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("role %s (%d): expected the mock to grant %v, got %v", name, role.ID, want, got)
+		}
+	}
+}
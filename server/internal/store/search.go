@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	SearchPosts = "posts"
+	SearchUsers = "users"
+	SearchTags  = "tags"
+)
+
+type SearchQuery struct {
+	Q      string `json:"q" validate:"required,max=100"`
+	Type   string `json:"type" validate:"oneof=posts users tags"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=50"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+func (q SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	qs := r.URL.Query()
+
+	q.Q = strings.TrimSpace(qs.Get("q"))
+
+	if t := qs.Get("type"); t != "" {
+		q.Type = t
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+// PostSearchResult is a matching post. Title and Snippet are HTML escaped,
+// with the matched terms wrapped in <mark> tags.
+type PostSearchResult struct {
+	ID        int64    `json:"id"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Title     string   `json:"title"`
+	Snippet   string   `json:"snippet"`
+	Tags      []string `json:"tags"`
+	CreatedAt string   `json:"created_at"`
+	Rank      float64  `json:"rank"`
+}
+
+type UserSearchResult struct {
+	ID       int64   `json:"id"`
+	Username string  `json:"username"`
+	Rank     float64 `json:"rank"`
+}
+
+type TagSearchResult struct {
+	Tag   string  `json:"tag"`
+	Posts int     `json:"posts"`
+	Rank  float64 `json:"rank"`
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10`
+
+// SearchPosts ranks the posts viewerID can see with full-text search. Titles
+// that only resemble the query, typos included, are matched through trigram
+// similarity.
+func (s *SearchStore) SearchPosts(ctx context.Context, viewerID int64, q SearchQuery) ([]PostSearchResult, error) {
+	query := `
+		SELECT
+			p.id, p.user_id, u.username,
+			ts_headline('english', ` + escapeHTML("p.title") + `, sq.tsq, 'HighlightAll=true, ` + headlineOptions + `'),
+			ts_headline('english', ` + escapeHTML("p.content") + `, sq.tsq, '` + headlineOptions + `'),
+			p.tags, p.created_at,
+			GREATEST(ts_rank(p.search_vector, sq.tsq), similarity(p.title, $1)) AS rank
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN (SELECT websearch_to_tsquery('english', $1) AS tsq) sq
+		WHERE
+			(p.search_vector @@ sq.tsq OR p.title % $1) AND
+			` + published("p") + ` AND
+			` + visibleTo("p.user_id", "$2") + `
+		ORDER BY rank DESC, p.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Q, viewerID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := []PostSearchResult{}
+	for rows.Next() {
+		var p PostSearchResult
+
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Username,
+			&p.Title,
+			&p.Snippet,
+			pq.Array(&p.Tags),
+			&p.CreatedAt,
+			&p.Rank,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, p)
+	}
+
+	return results, rows.Err()
+}
+
+// SearchUsers matches usernames by prefix or trigram similarity. Suspended
+// users and those who blocked viewerID, or were blocked by them, are left
+// out.
+func (s *SearchStore) SearchUsers(ctx context.Context, viewerID int64, q SearchQuery) ([]UserSearchResult, error) {
+	query := `
+		SELECT u.id, u.username, similarity(u.username, $1) AS rank
+		FROM users u
+		WHERE
+			u.is_active AND
+			(u.username ILIKE $5 || '%' OR u.username % $1) AND
+			` + notBlocked("u.id", "$2") + ` AND
+			` + notSuspended("u.id") + `
+		ORDER BY u.username ILIKE $5 || '%' DESC, rank DESC, u.id
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Q, viewerID, q.Limit, q.Offset, escapeLike(q.Q))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := []UserSearchResult{}
+	for rows.Next() {
+		var u UserSearchResult
+
+		if err := rows.Scan(&u.ID, &u.Username, &u.Rank); err != nil {
+			return nil, err
+		}
+
+		results = append(results, u)
+	}
+
+	return results, rows.Err()
+}
+
+// SearchTags matches the tags of the posts viewerID can see, counting how
+// many of those posts carry each tag.
+func (s *SearchStore) SearchTags(ctx context.Context, viewerID int64, q SearchQuery) ([]TagSearchResult, error) {
+	query := `
+		SELECT t.tag, COUNT(*) AS posts, similarity(t.tag, $1) AS rank
+		FROM posts p
+		CROSS JOIN LATERAL unnest(p.tags) AS t(tag)
+		WHERE
+			(t.tag ILIKE $5 || '%' OR t.tag % $1) AND
+			` + published("p") + ` AND
+			` + visibleTo("p.user_id", "$2") + `
+		GROUP BY t.tag
+		ORDER BY rank DESC, posts DESC, t.tag
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Q, viewerID, q.Limit, q.Offset, escapeLike(q.Q))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := []TagSearchResult{}
+	for rows.Next() {
+		var t TagSearchResult
+
+		if err := rows.Scan(&t.Tag, &t.Posts, &t.Rank); err != nil {
+			return nil, err
+		}
+
+		results = append(results, t)
+	}
+
+	return results, rows.Err()
+}
+
+// escapeHTML returns an SQL expression escaping the HTML in column, so that
+// the <mark> tags ts_headline adds are the only markup left in its output.
+func escapeHTML(column string) string {
+	return fmt.Sprintf(
+		`replace(replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`,
+		column,
+	)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match literally in a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
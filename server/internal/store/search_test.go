@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSearchPosts(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	author := createTestUser(t, db, "author")
+
+	post := &Post{
+		UserID:  author.ID,
+		Title:   `<script>alert(1)</script> gophers`,
+		Content: `<img src=x onerror=alert(1)> gophers & "friends"`,
+		Tags:    []string{},
+	}
+
+	posts := &PostsStore{db}
+	if err := posts.Create(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM posts WHERE id = $1`, post.ID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	search := &SearchStore{db}
+	results, err := search.SearchPosts(ctx, author.ID, SearchQuery{Q: "gophers", Type: SearchPosts, Limit: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result *PostSearchResult
+	for i := range results {
+		if results[i].ID == post.ID {
+			result = &results[i]
+		}
+	}
+
+	if result == nil {
+		t.Fatalf("expected post %d to match, got %+v", post.ID, results)
+	}
+
+	t.Run("should highlight the matched terms", func(t *testing.T) {
+		for _, s := range []string{result.Title, result.Snippet} {
+			if !strings.Contains(s, "<mark>gophers</mark>") {
+				t.Errorf("expected gophers to be highlighted in %q", s)
+			}
+		}
+	})
+
+	t.Run("should escape the post's HTML", func(t *testing.T) {
+		if strings.Contains(result.Title, "<script") || !strings.Contains(result.Title, "&lt;script&gt;") {
+			t.Errorf("expected the title's HTML to be escaped, got %q", result.Title)
+		}
+
+		if strings.Contains(result.Snippet, "<img") || !strings.Contains(result.Snippet, "&lt;img") {
+			t.Errorf("expected the snippet's HTML to be escaped, got %q", result.Snippet)
+		}
+	})
+}
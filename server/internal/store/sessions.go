@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionExpired = errors.New("session expired")
+	ErrSessionRevoked = errors.New("session revoked")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+)
+
+type Session struct {
+	ID        int64     `json:"id"`
+	FamilyID  string    `json:"family_id"`
+	UserID    int64     `json:"user_id"`
+	Token     string    `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt string    `json:"created_at"`
+}
+
+type SessionsStore struct {
+	db *sql.DB
+}
+
+func (s *SessionsStore) Create(ctx context.Context, session *Session) error {
+	query := `
+		INSERT INTO sessions (family_id, user_id, token, expiry)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		session.FamilyID,
+		session.UserID,
+		session.Token,
+		session.Expiry,
+	).Scan(
+		&session.ID,
+		&session.CreatedAt,
+	)
+}
+
+// Rotate exchanges the refresh token identified by token for next, which
+// inherits the family and user of the old session. Presenting a token that
+// was already rotated revokes the whole family.
+func (s *SessionsStore) Rotate(ctx context.Context, token string, next *Session) error {
+	var reused bool
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, family_id, user_id, expiry, rotated_at IS NOT NULL, revoked_at IS NOT NULL
+			FROM sessions
+			WHERE token = $1
+			FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var (
+			current          Session
+			rotated, revoked bool
+		)
+
+		err := tx.QueryRowContext(ctx, query, token).Scan(
+			&current.ID,
+			&current.FamilyID,
+			&current.UserID,
+			&current.Expiry,
+			&rotated,
+			&revoked,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if revoked {
+			return ErrSessionRevoked
+		}
+
+		if rotated {
+			reused = true
+			return ErrTokenReused
+		}
+
+		if time.Now().After(current.Expiry) {
+			return ErrSessionExpired
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET rotated_at = NOW() WHERE id = $1`, current.ID); err != nil {
+			return err
+		}
+
+		next.FamilyID = current.FamilyID
+		next.UserID = current.UserID
+
+		return tx.QueryRowContext(
+			ctx,
+			`INSERT INTO sessions (family_id, user_id, token, expiry) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+			next.FamilyID,
+			next.UserID,
+			next.Token,
+			next.Expiry,
+		).Scan(
+			&next.ID,
+			&next.CreatedAt,
+		)
+	})
+
+	if reused {
+		// the family is revoked outside of the rolled back transaction so the
+		// revocation sticks
+		if err := s.revokeFamilyByToken(ctx, token); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (s *SessionsStore) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (s *SessionsStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return revokeUserSessions(ctx, tx, userID)
+	})
+}
+
+// IsActive reports whether the session family still has a usable,
+// non-revoked refresh token.
+func (s *SessionsStore) IsActive(ctx context.Context, familyID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE family_id = $1 AND revoked_at IS NULL AND expiry > NOW()
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var active bool
+	if err := s.db.QueryRowContext(ctx, query, familyID).Scan(&active); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+func (s *SessionsStore) revokeFamilyByToken(ctx context.Context, token string) error {
+	query := `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND family_id = (SELECT family_id FROM sessions WHERE token = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, token)
+	return err
+}
+
+func revokeUserSessions(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
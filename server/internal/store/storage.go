@@ -10,6 +10,7 @@ import (
 var (
 	ErrNotFound          = errors.New("resource not found")
 	ErrConflict          = errors.New("resource already exists")
+	ErrEditConflict      = errors.New("resource was modified concurrently")
 	ErrDuplicateEmail    = errors.New("duplicate email")
 	ErrDuplicateUsername = errors.New("duplicate username")
 	QueryTimeoutDuration = time.Second * 5
@@ -19,38 +20,138 @@ type Storage struct {
 	Posts interface {
 		GetByID(context.Context, int64) (*Post, error)
 		Create(context.Context, *Post) error
-		Delete(context.Context, int64) error
-		Update(context.Context, *Post) error
+		Delete(ctx context.Context, postID, deletedBy int64, audit *AuditEvent) error
+		GetDeleted(ctx context.Context, postID int64, deletedSince time.Time) (*Post, error)
+		Restore(ctx context.Context, postID int64, deletedSince time.Time, audit *AuditEvent) error
+		PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+		Update(ctx context.Context, post *Post, editorID int64) error
+		GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error)
+		GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error)
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetTagPosts(ctx context.Context, tag string, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
 	}
 	Users interface {
 		GetByID(context.Context, int64) (*User, error)
 		Create(context.Context, *sql.Tx, *User) error
-		CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error
+		CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration, mail *OutboxMessage) error
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration, mail *OutboxMessage) error
+		ResetPassword(ctx context.Context, token string, user *User) error
+		ReissueInvitation(ctx context.Context, email, token string, invitationExp time.Duration, mail func(*User) (*OutboxMessage, error)) (*User, error)
+		DeleteUnactivated(ctx context.Context, expiredBefore time.Time) (int64, error)
+		GetProfileStats(ctx context.Context, userID int64) (*UserStats, error)
+		SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error
+		UpdateRole(ctx context.Context, userID int64, role *Role, changedBy int64, reason string, audit *AuditEvent) error
+		CanViewContent(ctx context.Context, viewerID, authorID int64) (bool, error)
 	}
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		GetByPostID(ctx context.Context, postID, viewerID int64) ([]Comment, error)
+		ListByPostID(ctx context.Context, postID, viewerID int64, q CommentsQuery) ([]Comment, error)
+		GetByID(context.Context, int64) (*Comment, error)
 		Create(context.Context, *Comment) error
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64) error
 	}
 	Followers interface {
 		Follow(ctx context.Context, followerID, userID int64) error
 		Unfollow(ctx context.Context, followerID, userID int64) error
+		GetFollowers(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error)
+		GetFollowing(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error)
+		GetFriends(ctx context.Context, userID, viewerID int64, q ConnectionsQuery) ([]Connection, error)
+		GetRelationship(ctx context.Context, viewerID, userID int64) (*Relationship, error)
+		RequestFollow(ctx context.Context, requesterID, userID int64) error
+		GetFollowRequests(ctx context.Context, userID int64, q ConnectionsQuery) ([]FollowRequest, error)
+		ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error
+		RejectFollowRequest(ctx context.Context, userID, requesterID int64) error
+	}
+	Reactions interface {
+		Add(ctx context.Context, postID, userID int64, kind string) error
+		Remove(ctx context.Context, postID, userID int64, kind string) error
+		GetSummary(ctx context.Context, postID, userID int64) (*ReactionSummary, error)
+		ListUsers(ctx context.Context, postID, viewerID int64, q ReactionsQuery) ([]Reaction, error)
+	}
+	Blocks interface {
+		Block(ctx context.Context, userID, blockedID int64) error
+		Unblock(ctx context.Context, userID, blockedID int64) error
+		IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+		GetBlocked(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error)
+		Mute(ctx context.Context, userID, mutedID int64) error
+		Unmute(ctx context.Context, userID, mutedID int64) error
+		GetMuted(ctx context.Context, userID int64, q ConnectionsQuery) ([]BlockedUser, error)
+	}
+	Search interface {
+		SearchPosts(ctx context.Context, viewerID int64, q SearchQuery) ([]PostSearchResult, error)
+		SearchUsers(ctx context.Context, viewerID int64, q SearchQuery) ([]UserSearchResult, error)
+		SearchTags(ctx context.Context, viewerID int64, q SearchQuery) ([]TagSearchResult, error)
+	}
+	Tags interface {
+		RefreshTrending(ctx context.Context, window time.Duration, limit int) error
+		GetTrending(ctx context.Context, limit int) ([]TrendingTag, error)
+	}
+	Reports interface {
+		Create(context.Context, *Report) error
+		GetByID(context.Context, int64) (*Report, error)
+		List(context.Context, ReportsQuery) ([]Report, error)
+		Assign(ctx context.Context, reportID, assigneeID, moderatorID int64, audit *AuditEvent) error
+		Resolve(ctx context.Context, reportID int64, res ReportResolution) (*Report, error)
+		GetLog(context.Context, ModerationLogQuery) ([]ModerationLogEntry, error)
+	}
+	Suspensions interface {
+		Create(ctx context.Context, suspension *Suspension, audit *AuditEvent) error
+		GetActive(ctx context.Context, userID int64) (*Suspension, error)
+		GetByUserID(ctx context.Context, userID int64) ([]Suspension, error)
+		Lift(ctx context.Context, userID, liftedBy int64, audit *AuditEvent) error
+		LiftExpired(context.Context) (int64, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
+		GetAll(context.Context) ([]Role, error)
+		Create(ctx context.Context, role *Role, audit *AuditEvent) error
+		GetChanges(ctx context.Context, userID int64) ([]RoleChange, error)
+		GetPermissions(context.Context) (map[int64][]string, error)
+	}
+	Sessions interface {
+		Create(context.Context, *Session) error
+		Rotate(ctx context.Context, token string, next *Session) error
+		RevokeFamily(ctx context.Context, familyID string) error
+		RevokeAllForUser(ctx context.Context, userID int64) error
+		IsActive(ctx context.Context, familyID string) (bool, error)
+	}
+	Audit interface {
+		Create(context.Context, *AuditEvent) error
+		List(context.Context, AuditQuery) ([]AuditEvent, error)
+		Export(ctx context.Context, q AuditQuery, fn func(*AuditEvent) error) error
+	}
+	Outbox interface {
+		Enqueue(context.Context, *OutboxMessage) error
+		ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error)
+		MarkSent(ctx context.Context, id int64) error
+		MarkFailed(ctx context.Context, id int64, reason string, nextAttempt time.Time, dead bool) error
+		List(context.Context, OutboxQuery) ([]OutboxMessage, error)
+		Retry(ctx context.Context, id int64) error
+		PurgeDeadData(ctx context.Context, deadBefore time.Time) (int64, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostsStore{db},
-		Users:     &UsersStore{db},
-		Comments:  &CommentsStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RolesStore{db},
+		Posts:       &PostsStore{db},
+		Users:       &UsersStore{db},
+		Comments:    &CommentsStore{db},
+		Followers:   &FollowerStore{db},
+		Reactions:   &ReactionsStore{db},
+		Blocks:      &BlocksStore{db},
+		Search:      &SearchStore{db},
+		Tags:        &TagsStore{db},
+		Reports:     &ReportsStore{db},
+		Suspensions: &SuspensionsStore{db},
+		Roles:       &RolesStore{db},
+		Sessions:    &SessionsStore{db},
+		Audit:       &AuditStore{db},
+		Outbox:      &OutboxStore{db},
 	}
 }
 
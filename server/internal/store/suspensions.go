@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	SuspensionKindSuspension = "suspension"
+	SuspensionKindBan        = "ban"
+)
+
+// Suspension keeps a user from using their account and hides their content
+// until it expires or is lifted. It never expires when ExpiresAt is nil.
+// Bans are suspensions that are meant to be permanent or long lasting.
+type Suspension struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Kind        string     `json:"kind"`
+	Reason      string     `json:"reason"`
+	SuspendedBy *int64     `json:"suspended_by"`
+	CreatedAt   string     `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LiftedAt    *string    `json:"lifted_at"`
+	LiftedBy    *int64     `json:"lifted_by"`
+}
+
+type SuspensionsStore struct {
+	db *sql.DB
+}
+
+const suspensionColumns = `id, user_id, kind, reason, suspended_by, created_at, expires_at, lifted_at, lifted_by`
+
+// activeSuspension is the SQL predicate matching the user_suspensions rows
+// in effect.
+const activeSuspension = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// Create suspends a user, signing them out of every session. It's recorded
+// in the moderation log on behalf of suspension.SuspendedBy, and in the
+// audit log as audit.
+func (s *SuspensionsStore) Create(ctx context.Context, suspension *Suspension, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if err := createSuspension(ctx, tx, suspension); err != nil {
+			return err
+		}
+
+		entry := &ModerationLogEntry{
+			Action:    ActionSuspend,
+			SubjectID: &suspension.UserID,
+			Note:      suspension.Reason,
+		}
+
+		if suspension.Kind == SuspensionKindBan {
+			entry.Action = ActionBan
+		}
+
+		if suspension.SuspendedBy != nil {
+			entry.ModeratorID = *suspension.SuspendedBy
+		}
+
+		if err := appendModerationLog(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// GetActive returns the user's suspension currently in effect, the one
+// lasting the longest if there are several, or ErrNotFound.
+func (s *SuspensionsStore) GetActive(ctx context.Context, userID int64) (*Suspension, error) {
+	query := `
+		SELECT ` + suspensionColumns + `
+		FROM user_suspensions
+		WHERE user_id = $1 AND ` + activeSuspension + `
+		ORDER BY expires_at DESC NULLS FIRST
+		LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var suspension Suspension
+
+	if err := scanSuspension(s.db.QueryRowContext(ctx, query, userID), &suspension); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &suspension, nil
+}
+
+// GetByUserID lists every suspension of the user, lifted ones included,
+// most recent first.
+func (s *SuspensionsStore) GetByUserID(ctx context.Context, userID int64) ([]Suspension, error) {
+	query := `
+		SELECT ` + suspensionColumns + `
+		FROM user_suspensions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suspensions := []Suspension{}
+	for rows.Next() {
+		var suspension Suspension
+
+		if err := scanSuspension(rows, &suspension); err != nil {
+			return nil, err
+		}
+
+		suspensions = append(suspensions, suspension)
+	}
+
+	return suspensions, rows.Err()
+}
+
+// Lift ends the user's suspensions in effect on behalf of liftedBy, recording
+// audit along with it. It returns ErrNotFound if there are none.
+func (s *SuspensionsStore) Lift(ctx context.Context, userID, liftedBy int64, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE user_suspensions SET lifted_at = NOW(), lifted_by = $2
+			WHERE user_id = $1 AND ` + activeSuspension + `
+		`
+
+		res, err := tx.ExecContext(ctx, query, userID, liftedBy)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		entry := &ModerationLogEntry{
+			ModeratorID: liftedBy,
+			Action:      ActionLift,
+			SubjectID:   &userID,
+		}
+
+		if err := appendModerationLog(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// LiftExpired marks the suspensions that ran out as lifted, at the time
+// they expired.
+func (s *SuspensionsStore) LiftExpired(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE user_suspensions SET lifted_at = expires_at
+		WHERE lifted_at IS NULL AND expires_at <= NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// createSuspension saves the suspension and revokes the user's sessions so
+// they're signed out right away.
+func createSuspension(ctx context.Context, tx *sql.Tx, suspension *Suspension) error {
+	query := `
+		INSERT INTO user_suspensions (user_id, kind, reason, suspended_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	if suspension.Kind == "" {
+		suspension.Kind = SuspensionKindSuspension
+	}
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		suspension.UserID,
+		suspension.Kind,
+		suspension.Reason,
+		suspension.SuspendedBy,
+		suspension.ExpiresAt,
+	).Scan(&suspension.ID, &suspension.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return revokeUserSessions(ctx, tx, suspension.UserID)
+}
+
+func scanSuspension(row rowScanner, s *Suspension) error {
+	return row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.Kind,
+		&s.Reason,
+		&s.SuspendedBy,
+		&s.CreatedAt,
+		&s.ExpiresAt,
+		&s.LiftedAt,
+		&s.LiftedBy,
+	)
+}
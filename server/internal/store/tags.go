@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTagsPerPost = 10
+	MaxTagLength   = 32
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// NormalizeTags case-folds tags, strips a leading '#' and drops duplicates,
+// keeping the order they were given in. Tags may only contain letters,
+// digits, '-' and '_'.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxTagsPerPost {
+		return nil, fmt.Errorf("%w: a post can't have more than %d tags", ErrInvalidTag, MaxTagsPerPost)
+	}
+
+	return normalized, nil
+}
+
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("%w: %q must be between 1 and %d characters", ErrInvalidTag, tag, MaxTagLength)
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", fmt.Errorf("%w: %q may only contain letters, digits, '-' and '_'", ErrInvalidTag, tag)
+		}
+	}
+
+	return tag, nil
+}
+
+type TrendingTag struct {
+	Tag         string `json:"tag"`
+	Posts       int    `json:"posts"`
+	Authors     int    `json:"authors"`
+	RefreshedAt string `json:"refreshed_at"`
+}
+
+type TagsStore struct {
+	db *sql.DB
+}
+
+// RefreshTrending recomputes the trending tags from the public posts
+// created within window. Tags are ranked by how many distinct authors used
+// them, so a single prolific poster can't make a tag trend.
+func (s *TagsStore) RefreshTrending(ctx context.Context, window time.Duration, limit int) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_tags`); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO trending_tags (tag, posts, authors)
+			SELECT t.tag, COUNT(*), COUNT(DISTINCT p.user_id)
+			FROM posts p
+			JOIN users u ON u.id = p.user_id
+			CROSS JOIN LATERAL unnest(p.tags) AS t(tag)
+			WHERE
+				p.created_at >= $1 AND
+				` + published("p") + ` AND
+				NOT u.is_private AND
+				` + notSuspended("p.user_id") + `
+			GROUP BY t.tag
+			ORDER BY COUNT(DISTINCT p.user_id) DESC, COUNT(*) DESC, t.tag
+			LIMIT $2
+		`
+
+		_, err := tx.ExecContext(ctx, query, time.Now().Add(-window), limit)
+		return err
+	})
+}
+
+func (s *TagsStore) GetTrending(ctx context.Context, limit int) ([]TrendingTag, error) {
+	query := `
+		SELECT tag, posts, authors, refreshed_at
+		FROM trending_tags
+		ORDER BY authors DESC, posts DESC, tag
+		LIMIT $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var t TrendingTag
+
+		if err := rows.Scan(&t.Tag, &t.Posts, &t.Authors, &t.RefreshedAt); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
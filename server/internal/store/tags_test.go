@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	t.Run("should case-fold and dedupe tags in order", func(t *testing.T) {
+		tags, err := NormalizeTags([]string{" Go ", "#golang", "go", "Café"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"go", "golang", "café"}
+		if !reflect.DeepEqual(tags, want) {
+			t.Errorf("expected %v, got %v", want, tags)
+		}
+	})
+
+	t.Run("should reject invalid tags", func(t *testing.T) {
+		cases := [][]string{
+			{""},
+			{"#"},
+			{"two words"},
+			{"a-very-long-tag-that-goes-over-the-limit"},
+			{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+		}
+
+		for _, tags := range cases {
+			if _, err := NormalizeTags(tags); !errors.Is(err, ErrInvalidTag) {
+				t.Errorf("expected %v to be rejected, got %v", tags, err)
+			}
+		}
+	})
+	t.Run("should count tags once they're deduped", func(t *testing.T) {
+		tags := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "#A", "B"}
+
+		normalized, err := NormalizeTags(tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(normalized) != MaxTagsPerPost {
+			t.Errorf("expected %d tags, got %v", MaxTagsPerPost, normalized)
+		}
+	})
+}
@@ -40,17 +40,33 @@ type User struct {
 	CreatedAt string   `json:"created_at"`
 	UpdatedAt string   `json:"updated_at"`
 	IsActive  bool     `json:"is_active"`
+	IsPrivate bool     `json:"is_private"`
+	Language  string   `json:"language"`
 	RoleID    int64    `json:"role_id"`
 	Role      Role     `json:"role"`
 }
 
+// UserStats are the counters shown on a user's profile.
+type UserStats struct {
+	Posts     int `json:"posts"`
+	Followers int `json:"followers"`
+	Following int `json:"following"`
+}
+
+type UserProfile struct {
+	*User
+	Stats UserStats `json:"stats"`
+	// Relationship is left out when users view their own profile
+	Relationship *Relationship `json:"relationship,omitempty"`
+}
+
 type UsersStore struct {
 	db *sql.DB
 }
 
 func (s *UsersStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	query := `
-		INSERT INTO users (username, password, email, role_id) VALUES($1, $2, $3, (SELECT id FROM roles WHERE name = $4)) RETURNING id, created_at
+		INSERT INTO users (username, password, email, language, role_id) VALUES($1, $2, $3, $4, (SELECT id FROM roles WHERE name = $5)) RETURNING id, created_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -61,7 +77,11 @@ func (s *UsersStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 		role = "user"
 	}
 
-	err := tx.QueryRowContext(ctx, query, user.Username, user.Password.hash, user.Email, role).Scan(
+	if user.Language == "" {
+		user.Language = "en"
+	}
+
+	err := tx.QueryRowContext(ctx, query, user.Username, user.Password.hash, user.Email, user.Language, role).Scan(
 		&user.ID,
 		&user.CreatedAt,
 	)
@@ -82,7 +102,7 @@ func (s *UsersStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 
 func (s *UsersStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	query := `
-		SELECT users.id, username, email, password, created_at, roles.*
+		SELECT users.id, username, email, password, created_at, language, is_private, roles.*
 		FROM users
 		JOIN roles ON (users.role_id = roles.id)
 		WHERE users.id = $1 AND is_active = true
@@ -93,9 +113,11 @@ func (s *UsersStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	err := s.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Username,
-		&user.Password.hash,
 		&user.Email,
+		&user.Password.hash,
 		&user.CreatedAt,
+		&user.Language,
+		&user.IsPrivate,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -114,7 +136,7 @@ func (s *UsersStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	return user, nil
 }
 
-func (s *UsersStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
+func (s *UsersStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration, mail *OutboxMessage) error {
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
 		// create the user
 		if err := s.Create(ctx, tx, user); err != nil {
@@ -126,6 +148,11 @@ func (s *UsersStore) CreateAndInvite(ctx context.Context, user *User, token stri
 			return err
 		}
 
+		// the invitation email is only queued if the user is committed
+		if err := enqueueOutboxMessage(ctx, tx, mail); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
@@ -155,6 +182,95 @@ func (s *UsersStore) Activate(ctx context.Context, token string) error {
 	})
 }
 
+// ReissueInvitation replaces any outstanding invitations of the inactive user
+// registered with email by a fresh one and queues the email composed by mail.
+func (s *UsersStore) ReissueInvitation(ctx context.Context, email, token string, invitationExp time.Duration, mail func(*User) (*OutboxMessage, error)) (*User, error) {
+	user := &User{}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, username, email, created_at, language FROM users
+			WHERE email = $1 AND is_active = false
+			FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.Language)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := s.deleteUserInvitations(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		if err := s.createUserInvitation(ctx, tx, token, invitationExp, user.ID); err != nil {
+			return err
+		}
+
+		msg, err := mail(user)
+		if err != nil {
+			return err
+		}
+
+		return enqueueOutboxMessage(ctx, tx, msg)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteUnactivated purges accounts that were never activated and whose
+// latest invitation expired before expiredBefore, freeing their email and
+// username. Accounts left without an invitation go by their registration
+// time instead, so a resent invitation keeps the account until it expires.
+func (s *UsersStore) DeleteUnactivated(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	var deleted int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			DELETE FROM users u
+			WHERE u.is_active = false
+				AND COALESCE(
+					(SELECT MAX(ui.expiry) FROM user_invitations ui WHERE ui.user_id = u.id),
+					u.created_at
+				) < $1
+		`
+
+		res, err := tx.ExecContext(ctx, query, expiredBefore)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		query = `
+			DELETE FROM user_invitations ui
+			WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = ui.user_id)
+		`
+		_, err = tx.ExecContext(ctx, query)
+		return err
+	})
+
+	return deleted, err
+}
+
 func (s *UsersStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
 	query := `
 		SELECT u.id, u.username, u.email, u.created_at, u.is_active
@@ -257,9 +373,105 @@ func (s *UsersStore) delete(ctx context.Context, tx *sql.Tx, userID int64) error
 	return nil
 }
 
+func (s *UsersStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration, mail *OutboxMessage) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// only the latest reset link stays valid
+		if err := s.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO password_resets (token, user_id, expiry) VALUES ($1, $2, $3)`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(exp)); err != nil {
+			return err
+		}
+
+		return enqueueOutboxMessage(ctx, tx, mail)
+	})
+}
+
+func (s *UsersStore) ResetPassword(ctx context.Context, token string, user *User) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// find the user this token belongs to
+		found, err := s.getUserFromPasswordReset(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		user.ID = found.ID
+		user.Username = found.Username
+		user.Email = found.Email
+
+		if err := s.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		// the token is single use, drop it along with any other outstanding ones
+		if err := s.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return revokeUserSessions(ctx, tx, user.ID)
+	})
+}
+
+func (s *UsersStore) getUserFromPasswordReset(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+	query := `
+		SELECT u.id, u.username, u.email
+		FROM users u
+		JOIN password_resets pr ON u.id = pr.user_id
+		WHERE pr.token = $1 AND pr.expiry > $2
+	`
+
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user := &User{}
+	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(&user.ID, &user.Username, &user.Email)
+
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (s *UsersStore) updatePassword(ctx context.Context, tx *sql.Tx, user *User) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, user.Password.hash, user.ID)
+
+	return err
+}
+
+func (s *UsersStore) deletePasswordResets(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM password_resets WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+
+	return err
+}
+
 func (s *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password, created_at FROM users
+		SELECT id, username, email, password, created_at, language FROM users
 		WHERE email = $1 AND is_active = true
 	`
 
@@ -273,6 +485,7 @@ func (s *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.Language,
 	)
 
 	if err != nil {
@@ -286,3 +499,120 @@ func (s *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error
 
 	return user, nil
 }
+
+func (s *UsersStore) GetProfileStats(ctx context.Context, userID int64) (*UserStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM posts p WHERE p.user_id = $1 AND ` + published("p") + `),
+			(SELECT COUNT(*) FROM followers WHERE user_id = $1),
+			(SELECT COUNT(*) FROM followers WHERE follower_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	stats := &UserStats{}
+
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&stats.Posts,
+		&stats.Followers,
+		&stats.Following,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// SetPrivacy makes an account private or public. Going public approves the
+// pending follow requests since they no longer need approval.
+func (s *UsersStore) SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `UPDATE users SET is_private = $1 WHERE id = $2`
+
+		if _, err := tx.ExecContext(ctx, query, isPrivate, userID); err != nil {
+			return err
+		}
+
+		if isPrivate {
+			return nil
+		}
+
+		query = `
+			WITH approved AS (
+				DELETE FROM follow_requests WHERE user_id = $1
+				RETURNING user_id, requester_id
+			)
+			INSERT INTO followers (user_id, follower_id)
+			SELECT user_id, requester_id FROM approved
+			ON CONFLICT DO NOTHING
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID)
+		return err
+	})
+}
+
+// UpdateRole moves the user to role on behalf of changedBy, recording the
+// change along with its reason and audit.
+func (s *UsersStore) UpdateRole(ctx context.Context, userID int64, role *Role, changedBy int64, reason string, audit *AuditEvent) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			WITH old AS (
+				SELECT id, role_id FROM users WHERE id = $1 FOR UPDATE
+			)
+			UPDATE users u SET role_id = $2
+			FROM old
+			WHERE u.id = old.id
+			RETURNING old.role_id
+		`
+
+		var oldRoleID int64
+
+		if err := tx.QueryRowContext(ctx, query, userID, role.ID).Scan(&oldRoleID); err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		query = `
+			INSERT INTO role_changes (user_id, old_role_id, new_role_id, changed_by, reason)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+
+		if _, err := tx.ExecContext(ctx, query, userID, oldRoleID, role.ID, changedBy, reason); err != nil {
+			return err
+		}
+
+		return createAuditEvent(ctx, tx, audit)
+	})
+}
+
+// CanViewContent reports whether viewerID may see the posts and comments of
+// authorID.
+func (s *UsersStore) CanViewContent(ctx context.Context, viewerID, authorID int64) (bool, error) {
+	// the parameters are compared to one another, which leaves postgres
+	// nothing to infer their type from
+	query := `SELECT ` + visibleTo("$2::bigint", "$1::bigint")
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var visible bool
+
+	if err := s.db.QueryRowContext(ctx, query, viewerID, authorID).Scan(&visible); err != nil {
+		return false, err
+	}
+
+	return visible, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestDB connects to the migrated database named by TEST_DB_ADDR, the
+// tests needing one being skipped when it isn't set.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// createTestUser registers a user that's deleted along with its follows and
+// blocks once the test is over.
+func createTestUser(t *testing.T, db *sql.DB, name string) *User {
+	t.Helper()
+
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	user := &User{
+		Username: fmt.Sprintf("%s%d", name, suffix),
+		Email:    fmt.Sprintf("%s%d@example.com", name, suffix),
+	}
+
+	if err := user.Password.Set("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	users := &UsersStore{db}
+	err := withTx(db, ctx, func(tx *sql.Tx) error {
+		return users.Create(ctx, tx, user)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM users WHERE id = $1`, user.ID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return user
+}
+
+func TestCanViewContent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	users := &UsersStore{db}
+	author := createTestUser(t, db, "author")
+	viewer := createTestUser(t, db, "viewer")
+
+	canView := func(viewerID, authorID int64) bool {
+		t.Helper()
+
+		visible, err := users.CanViewContent(ctx, viewerID, authorID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return visible
+	}
+
+	t.Run("should show public accounts to everyone", func(t *testing.T) {
+		if !canView(viewer.ID, author.ID) {
+			t.Error("expected a public account to be visible")
+		}
+	})
+
+	t.Run("should only show private accounts to their followers", func(t *testing.T) {
+		if err := users.SetPrivacy(ctx, author.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if canView(viewer.ID, author.ID) {
+			t.Error("expected a private account to be hidden from non followers")
+		}
+
+		if !canView(author.ID, author.ID) {
+			t.Error("expected users to see their own content")
+		}
+
+		followers := &FollowerStore{db}
+		if err := followers.Follow(ctx, viewer.ID, author.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if !canView(viewer.ID, author.ID) {
+			t.Error("expected a private account to be visible to its followers")
+		}
+	})
+
+	t.Run("should hide blocked users", func(t *testing.T) {
+		blocks := &BlocksStore{db}
+		if err := blocks.Block(ctx, author.ID, viewer.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if canView(viewer.ID, author.ID) || canView(author.ID, viewer.ID) {
+			t.Error("expected users who blocked one another not to see each other")
+		}
+	})
+}
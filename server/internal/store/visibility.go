@@ -0,0 +1,44 @@
+package store
+
+import "fmt"
+
+// visibleTo returns an SQL predicate that holds when the content written by
+// author can be seen by viewer. Both are SQL expressions, usually a column
+// and a query parameter. Users always see their own content. Otherwise
+// nothing is visible between users who blocked one another or while the
+// author is suspended, public accounts are visible to everyone and private
+// ones only to their approved followers.
+func visibleTo(author, viewer string) string {
+	return fmt.Sprintf(`(
+		%[1]s = %[2]s OR (
+			%[3]s AND %[4]s AND (
+				NOT EXISTS (SELECT 1 FROM users vu WHERE vu.id = %[1]s AND vu.is_private) OR
+				EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = %[1]s AND vf.follower_id = %[2]s)
+			)
+		)
+	)`, author, viewer, notBlocked(author, viewer), notSuspended(author))
+}
+
+// notSuspended returns an SQL predicate that holds unless user is currently
+// suspended or banned.
+func notSuspended(user string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_suspensions ns
+		WHERE ns.user_id = %s AND `+activeSuspension+`
+	)`, user)
+}
+
+// published returns an SQL predicate that holds for the posts that are
+// neither deleted nor hidden by a moderator. post is the posts table alias.
+func published(post string) string {
+	return fmt.Sprintf(`(%[1]s.deleted_at IS NULL AND %[1]s.hidden_at IS NULL)`, post)
+}
+
+// notBlocked returns an SQL predicate that holds unless either user has
+// blocked the other.
+func notBlocked(user, other string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_blocks nb
+		WHERE (nb.user_id = %[1]s AND nb.blocked_id = %[2]s) OR (nb.user_id = %[2]s AND nb.blocked_id = %[1]s)
+	)`, user, other)
+}